@@ -0,0 +1,41 @@
+package runner
+
+import "sync"
+
+// Aggregator folds the results of the tasks into an accumulator of type A
+// as they complete.
+type Aggregator[A any] struct {
+	m    sync.Mutex
+	acc  A
+	fold func(A, TaskResult) A
+}
+
+// NewAggregator returns an Aggregator starting from init and folding each
+// task result in with fold. Attach it to a runner with WithAggregator.
+func NewAggregator[A any](init A, fold func(A, TaskResult) A) *Aggregator[A] {
+	return &Aggregator[A]{acc: init, fold: fold}
+}
+
+// Aggregated returns the accumulated value. After Start returned on
+// completion it covers every task.
+func (a *Aggregator[A]) Aggregated() A {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.acc
+}
+
+// add folds res into the accumulator.
+func (a *Aggregator[A]) add(res TaskResult) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.acc = a.fold(a.acc, res)
+}
+
+// WithAggregator folds the result of every task into agg as it completes.
+// The fold function is always called from the same goroutine, one result
+// at a time, so it needs no locking of its own.
+func WithAggregator[A any](agg *Aggregator[A]) Option {
+	return func(r *Runner) {
+		r.aggregators = append(r.aggregators, agg.add)
+	}
+}
@@ -0,0 +1,24 @@
+package runner
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAggregatorSumsResults(t *testing.T) {
+	sum := NewAggregator(0, func(acc int, res TaskResult) int {
+		return acc + res.Value.(int)
+	})
+	r := NewWithOptions(time.Second, 4, WithAggregator(sum))
+	for i := 1; i <= 10; i++ {
+		i := i
+		r.AddKeyed(strconv.Itoa(i), func(int) (any, error) { return i, nil })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := sum.Aggregated(); got != 55 {
+		t.Fatalf("Aggregated() = %d, want 55", got)
+	}
+}
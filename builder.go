@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Builder configures a Runner through chained calls, as in
+//
+//	err := runner.Build().Timeout(3 * time.Second).Workers(4).Tasks(a, b).Run()
+//
+// A Builder is not safe for concurrent use.
+type Builder struct {
+	timeout time.Duration
+	workers int
+	opts    []Option
+	tasks   []func(int)
+}
+
+// Build returns a new Builder for a runner with a single worker.
+func Build() *Builder {
+	return &Builder{workers: 1}
+}
+
+// Timeout sets the time the run has to finish.
+func (b *Builder) Timeout(d time.Duration) *Builder {
+	b.timeout = d
+	return b
+}
+
+// Workers sets the number of workers executing tasks.
+func (b *Builder) Workers(n int) *Builder {
+	b.workers = n
+	return b
+}
+
+// Logger emits the lifecycle events on logger, see WithSlog.
+func (b *Builder) Logger(logger *slog.Logger) *Builder {
+	return b.With(WithSlog(logger))
+}
+
+// With applies opts to the runner, for the settings without a setter of
+// their own.
+func (b *Builder) With(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Tasks attaches tasks to the runner.
+func (b *Builder) Tasks(tasks ...func(int)) *Builder {
+	b.tasks = append(b.tasks, tasks...)
+	return b
+}
+
+// Runner returns a new Runner configured as set so far, with the tasks
+// attached but not started.
+func (b *Builder) Runner() *Runner {
+	r := NewWithOptions(b.timeout, b.workers, b.opts...)
+	r.Add(b.tasks...)
+	return r
+}
+
+// Run builds the Runner and starts it, returning the result of Start.
+func (b *Builder) Run() error {
+	return b.Runner().Start()
+}
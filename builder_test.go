@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	var ran atomic.Int32
+	task := func(int) { ran.Add(1) }
+	b := Build().
+		Timeout(3*time.Second).
+		Workers(4).
+		Logger(slog.New(&captureHandler{})).
+		With(WithTotalWorkBudget(time.Minute)).
+		Tasks(task, task, task)
+	got := b.Runner().Config()
+	want := RunnerConfig{
+		Timeout:        3 * time.Second,
+		Workers:        4,
+		WorkBudget:     time.Minute,
+		Logging:        true,
+		PanicRecovery:  true,
+		MainBuffer:     1,
+		SignalHandling: true,
+	}
+	if got != want {
+		t.Fatalf("Config() = %+v, want %+v", got, want)
+	}
+	if err := b.Run(); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if got := ran.Load(); got != 3 {
+		t.Fatalf("%d tasks ran, want 3", got)
+	}
+}
@@ -0,0 +1,16 @@
+package runner
+
+// burst spawns the standby worker when the queue has grown past the burst
+// depth while the run is in progress, r.m must be held.
+func (r *Runner) burst() {
+	id := r.numberOfWorker
+	if !r.dispatching || r.synchronous || r.cutoff != nil || r.alive[id] || r.queue.len() <= r.burstDepth {
+		return
+	}
+	r.spawn(id)
+}
+
+// standby reports whether id is the standby worker of WithBurstWorker.
+func (r *Runner) standby(id int) bool {
+	return r.burstWorker && id == r.numberOfWorker
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBurstWorker(t *testing.T) {
+	r := NewWithOptions(5*time.Second, 1, WithBurstWorker(3))
+	var starts, exits atomic.Int32
+	r.OnWorkerStart(func(id int) {
+		if id == 1 {
+			starts.Add(1)
+		}
+	})
+	r.OnWorkerExit(func(id int) {
+		if id == 1 {
+			exits.Add(1)
+		}
+	})
+	waitFor := func(what string, n *atomic.Int32, want int32) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); n.Load() < want; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("the standby worker %s %d times, want %d", what, n.Load(), want)
+			}
+		}
+	}
+	// the regular worker is held by a long task, keeping the run going
+	started, release := make(chan struct{}), make(chan struct{})
+	r.Add(func(int) {
+		close(started)
+		<-release
+	})
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	<-started
+
+	burst := func() {
+		for i := 0; i < 6; i++ {
+			r.Add(func(int) { time.Sleep(time.Millisecond) })
+		}
+	}
+	burst()
+	waitFor("started", &starts, 1)
+	waitFor("exited", &exits, 1)
+	if got := starts.Load(); got != 1 {
+		t.Fatalf("the standby worker started %d times during the lull, want 1", got)
+	}
+	burst()
+	waitFor("started", &starts, 2)
+	waitFor("exited", &exits, 2)
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	for _, res := range r.Results() {
+		if res.Worker == 1 {
+			return
+		}
+	}
+	t.Fatal("no task ran on the standby worker")
+}
+
+func TestBurstWorkerIdleUnderDepth(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithBurstWorker(5))
+	var standby atomic.Bool
+	r.OnWorkerStart(func(id int) {
+		if id == 1 {
+			standby.Store(true)
+		}
+	})
+	for i := 0; i < 5; i++ {
+		r.Add(func(int) {})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if standby.Load() {
+		t.Fatal("the standby worker started with no more tasks queued than its depth")
+	}
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CheckpointStore keeps the indices of the tasks a run has completed, so
+// that a run resumed after a restart can skip them.
+type CheckpointStore interface {
+	// Save replaces the stored indices with completed.
+	Save(completed []int) error
+
+	// Load returns the stored indices.
+	Load() ([]int, error)
+}
+
+// restore loads the indices of the tasks completed by an earlier run from
+// the store set with SkipCompleted.
+func (r *Runner) restore() ([]int, error) {
+	if r.skipStore == nil {
+		return nil, nil
+	}
+	completed, err := r.skipStore.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading checkpoint: %w", err)
+	}
+	return completed, nil
+}
+
+// skip marks the pending tasks at the indices completed as done without
+// running them, r.m must be held.
+func (r *Runner) skip(completed []int) {
+	for _, i := range completed {
+		if i < 0 || i >= len(r.tasks) {
+			continue
+		}
+		if t := r.tasks[i]; r.queue.remove(t) {
+			t.restored = true
+			t.close(r)
+		}
+	}
+}
+
+// checkpoint saves the indices of the completed tasks to the checkpoint
+// store every interval until done is closed. Start saves them a last time
+// before it returns.
+func (r *Runner) checkpoint(done <-chan struct{}) {
+	ticker := time.NewTicker(r.checkpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.saveCheckpoint()
+		case <-done:
+			return
+		}
+	}
+}
+
+// saveCheckpoint saves the indices of the tasks completed so far, whether
+// they ran successfully in this run or were skipped as completed earlier.
+func (r *Runner) saveCheckpoint() {
+	// saves are serialized so that an older snapshot never overwrites a
+	// newer one
+	r.saving.Lock()
+	defer r.saving.Unlock()
+	r.m.Lock()
+	var completed []int
+	for _, t := range r.tasks {
+		if t.restored || (t.ran && t.result.Err == nil) {
+			completed = append(completed, t.index)
+		}
+	}
+	r.m.Unlock()
+	if err := r.checkpointStore.Save(completed); err != nil {
+		r.log("saving checkpoint failed", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryCheckpoint is a CheckpointStore held in memory.
+type memoryCheckpoint struct {
+	mu        sync.Mutex
+	completed []int
+}
+
+func (s *memoryCheckpoint) Save(completed []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed = append([]int(nil), completed...)
+	return nil
+}
+
+func (s *memoryCheckpoint) Load() ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.completed...), nil
+}
+
+func TestCheckpointSkipsCompleted(t *testing.T) {
+	store := &memoryCheckpoint{}
+	var mu sync.Mutex
+	var ran []int
+	tasks := func(r *Runner, release chan struct{}) {
+		for i := 0; i < 6; i++ {
+			i := i
+			r.Add(func(int) {
+				if i == 3 && release != nil {
+					<-release
+					return
+				}
+				mu.Lock()
+				ran = append(ran, i)
+				mu.Unlock()
+			})
+		}
+	}
+
+	r := NewWithOptions(30*time.Millisecond, 1, WithCheckpoint(store, 5*time.Millisecond))
+	release := make(chan struct{})
+	tasks(r, release)
+	err := r.Start()
+	close(release)
+	if err != ErrTimeout {
+		t.Fatalf("Start() = %v, want %v", err, ErrTimeout)
+	}
+	if saved, _ := store.Load(); len(saved) != 3 {
+		t.Fatalf("the checkpoint holds %v, want tasks 0 to 2", saved)
+	}
+
+	ran = nil
+	r = NewWithOptions(time.Second, 1, WithCheckpoint(store, 5*time.Millisecond), SkipCompleted(store))
+	tasks(r, nil)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v on the resumed run, want nil", err)
+	}
+	if len(ran) != 3 || ran[0] != 3 || ran[1] != 4 || ran[2] != 5 {
+		t.Fatalf("the resumed run ran tasks %v, want 3 to 5", ran)
+	}
+}
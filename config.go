@@ -0,0 +1,228 @@
+package runner
+
+import "time"
+
+// RunnerConfig describes the effective configuration of a Runner.
+type RunnerConfig struct {
+	// Timeout is the time the run has to finish.
+	Timeout time.Duration
+
+	// Deadline is the absolute deadline set with WithDeadline, zero when
+	// unset.
+	Deadline time.Time
+
+	// Workers is the number of workers executing tasks.
+	Workers int
+
+	// WorkBudget is the cap on the cumulative task duration set with
+	// WithTotalWorkBudget, zero when unset.
+	WorkBudget time.Duration
+
+	// Quorum is the number of successful tasks set with WithQuorum,
+	// zero when unset.
+	Quorum int
+
+	// Logging reports whether a logger was set with WithSlog.
+	Logging bool
+
+	// Name is the name set with WithName, empty when unset.
+	Name string
+
+	// PanicHandler reports whether a handler was set with
+	// WithPanicHandler, PanicRecovery whether panics are recovered at all,
+	// which WithoutPanicRecovery turns off.
+	PanicHandler  bool
+	PanicRecovery bool
+
+	// ResultCache reports whether a cache was set with WithResultCache.
+	ResultCache bool
+
+	// TimeoutDrain reports whether the running tasks are waited for on a
+	// timeout, see WithTimeoutDrain.
+	TimeoutDrain bool
+
+	// MemoryGate is the heap size set with WithMemoryGate, zero when
+	// unset.
+	MemoryGate uint64
+
+	// StartJitter is the jitter set with WithStartJitter, zero when unset.
+	StartJitter time.Duration
+
+	// Source reports whether tasks are pulled from a source set with
+	// WithSource.
+	Source bool
+
+	// RetryPolicy is the policy set with WithRetryPolicy, zero when unset.
+	RetryPolicy RetryPolicy
+
+	// IdleTimeout is the time an idle worker waits before exiting, set
+	// with WithIdleTimeout, zero when unset.
+	IdleTimeout time.Duration
+
+	// YieldBetweenTasks reports whether workers yield the processor after
+	// each task, see WithYieldBetweenTasks.
+	YieldBetweenTasks bool
+
+	// Synchronous reports whether tasks run on the goroutine calling Start,
+	// see WithSynchronous.
+	Synchronous bool
+
+	// RetryOnPanic reports whether panicking tasks are retried, see
+	// WithRetryOnPanic.
+	RetryOnPanic bool
+
+	// CompletionPredicate reports whether a predicate was set with
+	// WithCompletionPredicate.
+	CompletionPredicate bool
+
+	// TaskFilter reports whether a filter was set with WithTaskFilter.
+	TaskFilter bool
+
+	// RetryBudget is the cap on the retries of a run set with
+	// WithRetryBudget, zero when unset.
+	RetryBudget int
+
+	// PersistentQueue reports whether a queue was set with
+	// WithPersistentQueue.
+	PersistentQueue bool
+
+	// HealthCheckInterval is the polling interval of the health check set
+	// with WithHealthCheck, zero when unset. HealthGrace is the time the check
+	// may fail for, set with WithHealthGrace.
+	HealthCheckInterval time.Duration
+	HealthGrace         time.Duration
+
+	// ConcurrentFailureLimit is the limit set with
+	// WithConcurrentFailureLimit, zero when unset.
+	ConcurrentFailureLimit int
+
+	// TimeSlice is the quantum of the sliced tasks set with WithTimeSlice,
+	// zero when unset.
+	TimeSlice time.Duration
+
+	// WorkerInit reports whether a function was set with WithWorkerInit.
+	WorkerInit bool
+
+	// ResultRingBuffer is the number of results retained, set with
+	// WithResultRingBuffer, zero when every result is kept.
+	ResultRingBuffer int
+
+	// SoftDeadline is the deadline set with WithSoftDeadline, zero when
+	// unset, and SoftGrace the time the running tasks get past it.
+	SoftDeadline time.Time
+	SoftGrace    time.Duration
+
+	// RequireAllStarted reports whether a timeout leaving tasks unstarted
+	// fails with an IncompleteRunError, see WithRequireAllStarted.
+	RequireAllStarted bool
+
+	// DefaultTask reports whether a task was set with WithDefaultTask.
+	DefaultTask bool
+
+	// ReplayTrace reports whether a trace is replayed, see WithReplayTrace.
+	ReplayTrace bool
+
+	// TaskTimeoutSoft reports whether the tasks running past their own
+	// timeout are kept from counting as failed, see WithTaskTimeoutSoft.
+	TaskTimeoutSoft bool
+
+	// CheckpointInterval is the interval set with WithCheckpoint, zero when
+	// unset. SkipCompleted reports whether a store was set with SkipCompleted.
+	CheckpointInterval time.Duration
+	SkipCompleted      bool
+
+	// RampUp is the delay between the start of two workers set with
+	// WithRampUp, zero when unset.
+	RampUp time.Duration
+
+	// RetryClassifier reports whether a classifier was set with
+	// WithRetryClassifier.
+	RetryClassifier bool
+
+	// AllocProfiling reports whether allocations are recorded per task,
+	// see WithAllocProfiling.
+	AllocProfiling bool
+
+	// DoubleInterruptForce is the window set with WithDoubleInterruptForce,
+	// zero when unset.
+	DoubleInterruptForce time.Duration
+
+	// GroupScorer reports whether a scorer was set with WithGroupScorer.
+	GroupScorer bool
+
+	// Preemption reports whether Critical tasks preempt running ones, see
+	// WithPreemption.
+	Preemption bool
+
+	// CompleteBuffer and MainBuffer are the buffers of the channels the
+	// workers report to the master goroutine on, see WithChannelBuffers.
+	CompleteBuffer int
+	MainBuffer     int
+
+	// SignalHandling reports whether the runner listens to operating system
+	// signals, which WithoutSignalHandling turns off.
+	SignalHandling bool
+
+	// BurstWorker reports whether a standby worker was added with
+	// WithBurstWorker, and BurstDepth is the queue depth it activates past.
+	BurstWorker bool
+	BurstDepth  int
+}
+
+// Config returns the effective configuration of r. It is safe to call at
+// any time.
+func (r *Runner) Config() RunnerConfig {
+	// completeMain is replaced by Restart
+	r.m.Lock()
+	defer r.m.Unlock()
+	return RunnerConfig{
+		Timeout:                r.timeoutDuration,
+		Deadline:               r.deadline,
+		Workers:                r.numberOfWorker,
+		WorkBudget:             r.workBudget,
+		Quorum:                 r.quorum,
+		Logging:                r.logger != nil,
+		Name:                   r.name,
+		PanicHandler:           r.panicHandler != nil,
+		PanicRecovery:          r.panicHandler != nil || r.retryOnPanic || !r.noRecover,
+		ResultCache:            r.cache != nil,
+		TimeoutDrain:           r.timeoutDrain,
+		MemoryGate:             r.maxHeap,
+		StartJitter:            r.startJitter,
+		Source:                 r.source != nil,
+		RetryPolicy:            r.retry,
+		IdleTimeout:            r.idleTimeout,
+		YieldBetweenTasks:      r.yield,
+		Synchronous:            r.synchronous,
+		RetryOnPanic:           r.retryOnPanic,
+		CompletionPredicate:    r.completed != nil,
+		TaskFilter:             r.filter != nil,
+		RetryBudget:            r.retryBudget,
+		PersistentQueue:        r.persist != nil,
+		HealthCheckInterval:    r.healthInterval,
+		HealthGrace:            r.healthGrace,
+		ConcurrentFailureLimit: r.failureLimit,
+		TimeSlice:              r.timeSlice,
+		WorkerInit:             r.workerInit != nil,
+		ResultRingBuffer:       r.ringSize,
+		SoftDeadline:           r.softDeadline,
+		SoftGrace:              r.softGrace,
+		RequireAllStarted:      r.requireAllStarted,
+		DefaultTask:            r.defaultTask != nil,
+		ReplayTrace:            r.replay != nil,
+		TaskTimeoutSoft:        r.softTaskTimeout,
+		CheckpointInterval:     r.checkpointInterval,
+		SkipCompleted:          r.skipStore != nil,
+		RampUp:                 r.rampUp,
+		RetryClassifier:        r.retryableErr != nil,
+		AllocProfiling:         r.allocProfiling,
+		DoubleInterruptForce:   r.forceWindow,
+		GroupScorer:            r.groupScorer != nil,
+		Preemption:             r.preemption,
+		CompleteBuffer:         cap(r.complete),
+		MainBuffer:             cap(r.completeMain),
+		SignalHandling:         !r.ignoreSignals,
+		BurstWorker:            r.burstWorker,
+		BurstDepth:             r.burstDepth,
+	}
+}
@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestConfigReflectsOptions(t *testing.T) {
+	soft := time.Now().Add(time.Hour)
+	r := NewWithOptions(3*time.Second, 4,
+		WithTotalWorkBudget(time.Second),
+		WithQuorum(2),
+		WithSlog(slog.Default()),
+		WithPanicHandler(func(int, any, []byte) {}),
+		WithoutPanicRecovery(),
+		WithResultCache(&mapCache{}),
+		WithTimeoutDrain(),
+		WithMemoryGate(1<<30),
+		WithStartJitter(time.Millisecond),
+		WithSource(&countingSource{}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3}),
+		WithIdleTimeout(time.Minute),
+		WithYieldBetweenTasks(),
+		WithSynchronous(),
+		WithRetryOnPanic(),
+		WithCompletionPredicate(func(RunnerMetrics) bool { return false }),
+		WithTaskFilter(func(_ int, task func(int)) func(int) { return task }),
+		WithRetryBudget(3),
+		WithPersistentQueue(NewMemoryQueue()),
+		WithHealthCheck(func() bool { return true }, time.Second),
+		WithHealthGrace(time.Minute),
+		WithConcurrentFailureLimit(5),
+		WithTimeSlice(time.Millisecond),
+		WithWorkerInit(func(int) any { return nil }),
+		WithResultRingBuffer(8),
+		WithSoftDeadline(soft, time.Minute),
+		WithRequireAllStarted(),
+		WithDefaultTask(func(int) {}),
+		WithReplayTrace(NewTrace(nil)),
+		WithTaskTimeoutSoft(),
+		WithCheckpoint(&memoryCheckpoint{}, time.Second),
+		SkipCompleted(&memoryCheckpoint{}),
+		WithRampUp(time.Millisecond),
+		WithRetryClassifier(func(error) bool { return true }),
+		WithAllocProfiling(),
+		WithDoubleInterruptForce(time.Second),
+		WithGroupScorer(func(string, []TaskResult) float64 { return 0 }),
+		WithPreemption(),
+		WithName("ingest"),
+		WithChannelBuffers(64, 2),
+		WithoutSignalHandling(),
+		WithBurstWorker(16),
+	)
+	got := r.Config()
+	want := RunnerConfig{
+		Timeout:                3 * time.Second,
+		Workers:                4,
+		WorkBudget:             time.Second,
+		Quorum:                 2,
+		Logging:                true,
+		PanicHandler:           true,
+		PanicRecovery:          true,
+		ResultCache:            true,
+		TimeoutDrain:           true,
+		MemoryGate:             1 << 30,
+		StartJitter:            time.Millisecond,
+		Source:                 true,
+		RetryPolicy:            RetryPolicy{MaxAttempts: 3},
+		IdleTimeout:            time.Minute,
+		YieldBetweenTasks:      true,
+		Synchronous:            true,
+		RetryOnPanic:           true,
+		CompletionPredicate:    true,
+		TaskFilter:             true,
+		RetryBudget:            3,
+		PersistentQueue:        true,
+		HealthCheckInterval:    time.Second,
+		HealthGrace:            time.Minute,
+		ConcurrentFailureLimit: 5,
+		TimeSlice:              time.Millisecond,
+		WorkerInit:             true,
+		ResultRingBuffer:       8,
+		SoftDeadline:           soft,
+		SoftGrace:              time.Minute,
+		RequireAllStarted:      true,
+		DefaultTask:            true,
+		ReplayTrace:            true,
+		TaskTimeoutSoft:        true,
+		CheckpointInterval:     time.Second,
+		SkipCompleted:          true,
+		RampUp:                 time.Millisecond,
+		RetryClassifier:        true,
+		AllocProfiling:         true,
+		DoubleInterruptForce:   time.Second,
+		GroupScorer:            true,
+		Preemption:             true,
+		Name:                   "ingest",
+		CompleteBuffer:         64,
+		MainBuffer:             2,
+		SignalHandling:         false,
+		BurstWorker:            true,
+		BurstDepth:             16,
+	}
+	if got != want {
+		t.Fatalf("Config() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	got := New(time.Second, 1).Config()
+	want := RunnerConfig{Timeout: time.Second, Workers: 1, PanicRecovery: true, MainBuffer: 1, SignalHandling: true}
+	if got != want {
+		t.Fatalf("Config() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigWithoutPanicRecovery(t *testing.T) {
+	if NewWithOptions(time.Second, 1, WithoutPanicRecovery()).Config().PanicRecovery {
+		t.Fatal("PanicRecovery = true under WithoutPanicRecovery")
+	}
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTaskCanceled is recorded for a context-aware task whose context was
+// canceled with CancelTask. WaitTask also returns it for a task canceled
+// through its handle.
+var ErrTaskCanceled = errors.New("task canceled")
+
+// AddContextTask attaches context-aware tasks. Each task gets its own
+// context, derived from the run's context, which is canceled when the run
+// ends or when the task is singled out with CancelTask.
+func (r *Runner) AddContextTask(tasks ...func(ctx context.Context, id int)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		t := r.push(nil)
+		t.cancelable = true
+		t.efn = func(id int) (any, error) {
+			ctx, cancel := r.taskContext(t)
+			defer cancel()
+			fn(ctx, id)
+			return nil, r.canceledErr(t)
+		}
+	}
+}
+
+// AddContext attaches tasks like Add unless ctx is already done, in which
+// case none of them is added and the error of ctx is returned. This keeps
+// the work of an abandoned request from being queued.
+func (r *Runner) AddContext(ctx context.Context, tasks ...func(int)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.Add(tasks...)
+	return nil
+}
+
+// CancelTask cancels the context of the task at index, without affecting
+// the other tasks, and reports whether it did. A task that has not started
+// yet starts with a canceled context. Only context-aware tasks can be
+// canceled; tasks taking a plain int cannot be interrupted once started.
+func (r *Runner) CancelTask(index int) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if index < 0 || index >= len(r.tasks) {
+		return false
+	}
+	t := r.tasks[index]
+	if !t.cancelable || t.finished {
+		return false
+	}
+	t.canceled = true
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return true
+}
+
+// taskContext returns the context t runs with and the function releasing
+// it, which the caller must call once the task has returned.
+func (r *Runner) taskContext(t *task) (context.Context, context.CancelFunc) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	ctx, cancel := context.WithCancel(r.ctx)
+	if t.canceled {
+		cancel()
+	}
+	t.cancel = cancel
+	return ctx, func() {
+		r.m.Lock()
+		t.cancel = nil
+		r.m.Unlock()
+		cancel()
+	}
+}
+
+// canceledErr returns ErrTaskCanceled if t was canceled with CancelTask.
+func (r *Runner) canceledErr(t *task) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if t.canceled {
+		return ErrTaskCanceled
+	}
+	return nil
+}
+
+// yieldMargin is how close to the deadline ShouldYield tells tasks to
+// stop.
+const yieldMargin = 10 * time.Millisecond
+
+// ShouldYield reports whether a long-running task should stop early: once
+// ctx is done, once the run has ended or stopped dispatching, or once the
+// deadline of the run or of ctx is less than 10ms away. It is meant to be
+// polled from the loops of CPU-bound tasks, which cannot select on a
+// channel, and is cheap enough to be called often.
+func (r *Runner) ShouldYield(ctx context.Context) bool {
+	if ctx != nil {
+		if ctx.Err() != nil {
+			return true
+		}
+		if d, ok := ctx.Deadline(); ok && time.Until(d) < yieldMargin {
+			return true
+		}
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	select {
+	case <-r.done:
+		return true
+	default:
+	}
+	if r.cutoff != nil {
+		return true
+	}
+	return !r.runDeadline.IsZero() && time.Until(r.runDeadline) < yieldMargin
+}
+
+// Context returns a context that is canceled once done is closed, such as
+// the channel returned by Runner.Done, saving cooperative tasks the select
+// boilerplate. It carries no deadline nor values, and unlike a context
+// derived with context.WithCancel it does not start a goroutine.
+func Context(done <-chan struct{}) context.Context {
+	return doneContext{done}
+}
+
+// doneContext is the context returned by Context.
+type doneContext struct {
+	done <-chan struct{}
+}
+
+func (doneContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c doneContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c doneContext) Err() error {
+	select {
+	case <-c.done:
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+func (doneContext) Value(key any) any {
+	return nil
+}
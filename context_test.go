@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCancelTask(t *testing.T) {
+	r := New(5*time.Second, 3)
+	started := make(chan struct{}, 3)
+	aborted := make([]bool, 3)
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		i := i
+		r.AddContextTask(func(ctx context.Context, id int) {
+			started <- struct{}{}
+			select {
+			case <-ctx.Done():
+				aborted[i] = true
+			case <-release:
+			}
+		})
+	}
+	go func() {
+		for i := 0; i < 3; i++ {
+			<-started
+		}
+		r.CancelTask(1)
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if aborted[0] || !aborted[1] || aborted[2] {
+		t.Fatalf("aborted = %v, want only task 1", aborted)
+	}
+	results := r.Results()
+	if !errors.Is(results[1].Err, ErrTaskCanceled) || results[0].Err != nil || results[2].Err != nil {
+		t.Fatalf("errors = %v, %v, %v; want only task 1 canceled", results[0].Err, results[1].Err, results[2].Err)
+	}
+	if r.CancelTask(0) {
+		t.Fatal("CancelTask() = true for a finished task")
+	}
+}
+
+func TestCancelTaskPlainTask(t *testing.T) {
+	r := New(time.Second, 1)
+	r.Add(func(int) {})
+	if r.CancelTask(0) {
+		t.Fatal("CancelTask() = true for a task without a context")
+	}
+}
+
+func TestTaskContextCanceled(t *testing.T) {
+	tests := []struct {
+		name string
+		add  func(r *Runner, n *fakeNotifier, watch func(context.Context))
+	}{
+		{"completion", func(r *Runner, n *fakeNotifier, watch func(context.Context)) {
+			r.AddContextTask(func(ctx context.Context, id int) { watch(ctx) })
+		}},
+		{"timeout", func(r *Runner, n *fakeNotifier, watch func(context.Context)) {
+			r.AddWithTimeout(10*time.Millisecond, func(ctx context.Context, id int, setPartial func(any)) (any, error) {
+				watch(ctx)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+		}},
+		{"interrupt", func(r *Runner, n *fakeNotifier, watch func(context.Context)) {
+			r.AddContextTask(func(ctx context.Context, id int) {
+				watch(ctx)
+				go n.send(t, os.Interrupt)
+				<-ctx.Done()
+			})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &fakeNotifier{}
+			r := NewWithOptions(time.Second, 1, WithNotifier(n))
+			canceled := make(chan struct{})
+			tt.add(r, n, func(ctx context.Context) {
+				context.AfterFunc(ctx, func() { close(canceled) })
+			})
+			r.Start()
+			select {
+			case <-canceled:
+			case <-time.After(time.Second):
+				t.Fatal("the task context was not canceled")
+			}
+		})
+	}
+}
+
+func TestAddContextCanceled(t *testing.T) {
+	r := New(time.Second, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := r.AddContext(ctx, func(int) {}); err != nil {
+		t.Fatalf("AddContext() = %v with a live context, want nil", err)
+	}
+	cancel()
+	if err := r.AddContext(ctx, func(int) {}, func(int) {}); err != context.Canceled {
+		t.Fatalf("AddContext() = %v, want %v", err, context.Canceled)
+	}
+	if got := r.Metrics().Registered; got != 1 {
+		t.Fatalf("%d tasks registered, want only the one added before the cancellation", got)
+	}
+}
+
+func TestContextFromDone(t *testing.T) {
+	r := New(20*time.Millisecond, 1)
+	taskErr := make(chan error, 1)
+	r.Add(func(int) {
+		ctx := Context(r.Done())
+		<-ctx.Done()
+		taskErr <- ctx.Err()
+	})
+	if err := r.Start(); err != ErrTimeout {
+		t.Fatalf("Start() = %v, want %v", err, ErrTimeout)
+	}
+	if err := <-taskErr; err != context.Canceled {
+		t.Fatalf("ctx.Err() = %v in the task, want %v", err, context.Canceled)
+	}
+}
+
+func TestShouldYieldNearDeadline(t *testing.T) {
+	r := New(60*time.Millisecond, 1)
+	var stoppedAfter time.Duration
+	r.AddContextTask(func(ctx context.Context, id int) {
+		start := time.Now()
+		for !r.ShouldYield(ctx) {
+			time.Sleep(100 * time.Microsecond)
+		}
+		stoppedAfter = time.Since(start)
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil for a task that yielded in time", err)
+	}
+	if stoppedAfter < 40*time.Millisecond {
+		t.Fatalf("the task yielded after %v, want it to run until the deadline was near", stoppedAfter)
+	}
+}
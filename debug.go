@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// debugSnapshot is the state of a runner as dumped by DebugSnapshot.
+type debugSnapshot struct {
+	Registered  int           `json:"registered"`
+	Pending     int           `json:"pending"`
+	Running     []int         `json:"running"`
+	Succeeded   int           `json:"succeeded"`
+	Failed      int           `json:"failed"`
+	Elapsed     string        `json:"elapsed"`
+	Dispatching bool          `json:"dispatching"`
+	Paused      bool          `json:"paused"`
+	Workers     []debugWorker `json:"workers"`
+}
+
+// debugWorker is the state of a worker as dumped by DebugSnapshot.
+type debugWorker struct {
+	ID int `json:"id"`
+	// Status is "running", "idle" or "stopped".
+	Status string `json:"status"`
+	// Task is the index of the running task, -1 when there is none.
+	Task int `json:"task"`
+}
+
+// DebugSnapshot returns the current state of r as JSON, for diagnostic
+// endpoints: the task counts, the indices of the running tasks, the time
+// elapsed since the run started and the status of each worker. It is safe
+// to call at any time, the snapshot is taken under the lock of the runner
+// and is consistent.
+func (r *Runner) DebugSnapshot() ([]byte, error) {
+	r.m.Lock()
+	m := r.metrics()
+	s := debugSnapshot{
+		Registered:  m.Registered,
+		Pending:     m.Pending,
+		Running:     []int{},
+		Succeeded:   m.Succeeded,
+		Failed:      m.Failed,
+		Elapsed:     m.Elapsed.Round(time.Millisecond).String(),
+		Dispatching: r.dispatching,
+		Paused:      r.paused,
+		Workers:     make([]debugWorker, len(r.busy)),
+	}
+	for id, t := range r.busy {
+		w := debugWorker{ID: id, Status: "stopped", Task: -1}
+		switch {
+		case t != nil:
+			w.Status, w.Task = "running", t.index
+			s.Running = append(s.Running, t.index)
+		case r.alive[id]:
+			w.Status = "idle"
+		}
+		s.Workers[id] = w
+	}
+	r.m.Unlock()
+	return json.Marshal(s)
+}
+
+// release records that worker id is no longer running a task, r.m must be
+// held.
+func (r *Runner) release(id int) {
+	if id < len(r.busy) {
+		r.busy[id] = nil
+	}
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDebugSnapshotMidRun(t *testing.T) {
+	r := New(time.Second, 2)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	hold := func(int) {
+		started <- struct{}{}
+		<-release
+	}
+	r.Add(hold)
+	r.Add(func(int) {})
+	r.AddFallible(func(int) error { return errors.New("boom") })
+	r.Add(hold, func(int) {}, func(int) {})
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	<-started
+	<-started
+	// the master goroutine records the finished tasks shortly after
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if m := r.Metrics(); m.Succeeded+m.Failed == 2 {
+			break
+		}
+	}
+
+	data, err := r.DebugSnapshot()
+	close(release)
+	if err != nil {
+		t.Fatalf("DebugSnapshot() = %v, want nil", err)
+	}
+	var s debugSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("DebugSnapshot() produced invalid JSON %s: %v", data, err)
+	}
+	sort.Ints(s.Running)
+	if s.Registered != 6 || s.Pending != 2 || s.Succeeded != 1 || s.Failed != 1 || !s.Dispatching {
+		t.Fatalf("snapshot = %s, want 6 registered, 2 pending, 1 succeeded, 1 failed", data)
+	}
+	if len(s.Running) != 2 || s.Running[0] != 0 || s.Running[1] != 3 {
+		t.Fatalf("snapshot running = %v, want [0 3]", s.Running)
+	}
+	if _, err := time.ParseDuration(s.Elapsed); err != nil {
+		t.Fatalf("snapshot elapsed = %q: %v", s.Elapsed, err)
+	}
+	if len(s.Workers) != 2 || s.Workers[0].Status != "running" || s.Workers[1].Status != "running" {
+		t.Fatalf("snapshot workers = %+v, want both running", s.Workers)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,74 @@
+package runner
+
+import "fmt"
+
+// errorStreamSize is the buffer of the channel returned by ErrorStream.
+const errorStreamSize = 64
+
+// TaskError is a task failure sent on the channel returned by ErrorStream.
+type TaskError struct {
+	// Index is the index of the task that failed.
+	Index int
+
+	// Err is the error the task failed with.
+	Err error
+}
+
+// Error returns the error of the task, prefixed with its index.
+func (e TaskError) Error() string {
+	return fmt.Sprintf("task %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the task.
+func (e TaskError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorStream returns a channel on which each task failure is sent as it
+// is recorded, that is as the task finishes, so that failures can be acted
+// upon while the run is in progress. The channel is closed once the last
+// task of the run has finished. The channel is buffered, and a failure
+// arriving while the buffer is full is dropped rather than holding the run
+// up, see DroppedErrors. It must be called before Start, and covers one
+// run.
+func (r *Runner) ErrorStream() <-chan TaskError {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.errStream = make(chan TaskError, errorStreamSize)
+	r.droppedErrors = 0
+	return r.errStream
+}
+
+// DroppedErrors returns the number of failures the stream returned by
+// ErrorStream has dropped because it was full.
+func (r *Runner) DroppedErrors() int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.droppedErrors
+}
+
+// streamError sends e on the error stream, if any, or counts it as dropped
+// when the stream is full.
+func (r *Runner) streamError(e TaskError) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.errStream == nil {
+		return
+	}
+	select {
+	case r.errStream <- e:
+	default:
+		r.droppedErrors++
+	}
+}
+
+// closeErrors closes the error stream once no task of the run is left to
+// report.
+func (r *Runner) closeErrors() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.errStream != nil {
+		close(r.errStream)
+		r.errStream = nil
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestErrorStream(t *testing.T) {
+	r := New(time.Second, 2)
+	errs := r.ErrorStream()
+	for i := 0; i < 6; i++ {
+		i := i
+		r.AddFallible(func(int) error {
+			if i%2 == 0 {
+				return fmt.Errorf("task %d failed", i)
+			}
+			return nil
+		})
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	streamed := make(map[int]error)
+	for e := range errs {
+		streamed[e.Index] = e.Err
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(streamed) != 3 {
+		t.Fatalf("%d failures streamed, want 3", len(streamed))
+	}
+	for _, i := range []int{0, 2, 4} {
+		if want := fmt.Sprintf("task %d failed", i); streamed[i] == nil || streamed[i].Error() != want {
+			t.Fatalf("task %d streamed %v, want %q", i, streamed[i], want)
+		}
+	}
+}
+
+func TestErrorStreamDropsWhenFull(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithSynchronous())
+	errs := r.ErrorStream()
+	const n = errorStreamSize + 10
+	for i := 0; i < n; i++ {
+		r.AddFallible(func(int) error { return errors.New("failed") })
+	}
+	// nobody reads the stream until the run is over
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	streamed := 0
+	for range errs {
+		streamed++
+	}
+	if streamed != errorStreamSize || r.DroppedErrors() != n-errorStreamSize {
+		t.Fatalf("%d failures streamed and %d dropped, want %d and %d",
+			streamed, r.DroppedErrors(), errorStreamSize, n-errorStreamSize)
+	}
+}
+
+func TestTaskErrorUnwraps(t *testing.T) {
+	errBoom := errors.New("boom")
+	e := TaskError{Index: 3, Err: errBoom}
+	if !errors.Is(e, errBoom) || e.Error() != "task 3: boom" {
+		t.Fatalf("TaskError = %q, want it to read and wrap %q", e.Error(), errBoom)
+	}
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrConcurrentFailures is returned when as many tasks as the limit set
+// with WithConcurrentFailureLimit failed while running at the same time.
+var ErrConcurrentFailures = errors.New("too many concurrent task failures")
+
+// span is the time a task was running.
+type span struct {
+	start, end time.Time
+}
+
+// overloaded records the failed run of a task over s and reports whether
+// the last failures, as many as the concurrent failure limit, were all
+// running at the same time, r.m must be held.
+func (r *Runner) overloaded(s span) bool {
+	if len(r.failures) == r.failureLimit {
+		r.failures = append(r.failures[:0], r.failures[1:]...)
+	}
+	r.failures = append(r.failures, s)
+	if len(r.failures) < r.failureLimit {
+		return false
+	}
+	// the runs overlap when they all started before any of them ended
+	latest, earliest := r.failures[0].start, r.failures[0].end
+	for _, f := range r.failures[1:] {
+		if f.start.After(latest) {
+			latest = f.start
+		}
+		if f.end.Before(earliest) {
+			earliest = f.end
+		}
+	}
+	return latest.Before(earliest)
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentFailureLimit(t *testing.T) {
+	r := NewWithOptions(time.Second, 3, WithConcurrentFailureLimit(3))
+	var burst sync.WaitGroup
+	burst.Add(3)
+	for i := 0; i < 3; i++ {
+		r.AddFallible(func(int) error {
+			burst.Done()
+			burst.Wait()
+			time.Sleep(10 * time.Millisecond)
+			return errors.New("dependency down")
+		})
+	}
+	var ran atomic.Int32
+	for i := 0; i < 10; i++ {
+		r.Add(func(int) {
+			time.Sleep(5 * time.Millisecond)
+			ran.Add(1)
+		})
+	}
+	if err := r.Start(); err != ErrConcurrentFailures {
+		t.Fatalf("Start() = %v, want %v", err, ErrConcurrentFailures)
+	}
+	if got := ran.Load(); got == 10 {
+		t.Fatal("every pending task ran after the burst of failures")
+	}
+}
+
+func TestSequentialFailuresBelowLimit(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithConcurrentFailureLimit(2))
+	for i := 0; i < 4; i++ {
+		r.AddFallible(func(int) error { return errors.New("bad task") })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil for failures that never overlapped", err)
+	}
+}
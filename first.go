@@ -0,0 +1,44 @@
+package runner
+
+import "errors"
+
+// ErrNoResult is returned by First when the run ended before any task
+// finished.
+var ErrNoResult = errors.New("no task finished")
+
+// First runs the attached tasks and returns the result of the first one to
+// finish, whether it succeeded or failed, as soon as it does. The run is
+// then stopped as with Stop, which cancels the context of the remaining
+// context-aware tasks, so that redundant operations can be raced against
+// each other. When the run ends before any task has finished, First
+// returns the error Start returned or, if there was none, ErrNoResult.
+func (r *Runner) First() (TaskResult, error) {
+	first := make(chan TaskResult, 1)
+	r.m.Lock()
+	r.first = first
+	r.m.Unlock()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.Start()
+	}()
+	select {
+	case res := <-first:
+		r.Stop()
+		<-errc
+		return res, nil
+	case err := <-errc:
+		// the first task may have finished as the run ended
+		select {
+		case res := <-first:
+			return res, nil
+		default:
+		}
+		r.m.Lock()
+		r.first = nil
+		r.m.Unlock()
+		if err == nil {
+			err = ErrNoResult
+		}
+		return TaskResult{}, err
+	}
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFirstReturnsFastest(t *testing.T) {
+	r := New(5*time.Second, 3)
+	canceled := make(chan bool, 3)
+	for _, d := range []time.Duration{200, 10, 100} {
+		d := d * time.Millisecond
+		r.AddContextTask(func(ctx context.Context, id int) {
+			select {
+			case <-time.After(d):
+				canceled <- false
+			case <-ctx.Done():
+				canceled <- true
+			}
+		})
+	}
+	start := time.Now()
+	res, err := r.First()
+	if err != nil {
+		t.Fatalf("First() = %v, want nil", err)
+	}
+	if res.Index != 1 {
+		t.Fatalf("First() returned task %d, want the fastest, 1", res.Index)
+	}
+	if d := time.Since(start); d > 90*time.Millisecond {
+		t.Fatalf("First() took %v, want it back before the slower tasks finish", d)
+	}
+	<-canceled // the fastest task
+	for i := 0; i < 2; i++ {
+		if !<-canceled {
+			t.Fatal("a slower task ran to its end, want it canceled")
+		}
+	}
+}
+
+func TestFirstWithoutResult(t *testing.T) {
+	r := New(20*time.Millisecond, 1)
+	release := make(chan struct{})
+	defer close(release)
+	r.Add(func(int) { <-release })
+	if _, err := r.First(); err != ErrTimeout {
+		t.Fatalf("First() = %v, want %v", err, ErrTimeout)
+	}
+	if _, err := New(time.Second, 1).First(); err != ErrNoResult {
+		t.Fatalf("First() = %v without tasks, want %v", err, ErrNoResult)
+	}
+}
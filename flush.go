@@ -0,0 +1,38 @@
+package runner
+
+// Flush blocks until every task queued so far has been handed to a worker
+// or canceled, that is until Metrics reports no pending task, or until the
+// run ends. Unlike Wait it does not wait for the tasks to finish. It
+// returns nil once the queue is empty and, if the run ended first, the
+// error the run ended with.
+func (r *Runner) Flush() error {
+	r.m.Lock()
+	if r.backlog() == 0 {
+		r.m.Unlock()
+		return nil
+	}
+	c := make(chan struct{})
+	r.flushed = append(r.flushed, c)
+	done := r.done
+	r.m.Unlock()
+	select {
+	case <-c:
+		return nil
+	case <-done:
+		r.m.Lock()
+		defer r.m.Unlock()
+		return r.endedWith
+	}
+}
+
+// releaseFlush wakes the Flush calls once nothing is pending, r.m must be
+// held.
+func (r *Runner) releaseFlush() {
+	if len(r.flushed) == 0 || r.backlog() > 0 {
+		return
+	}
+	for _, c := range r.flushed {
+		close(c)
+	}
+	r.flushed = nil
+}
@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlush(t *testing.T) {
+	r := New(time.Second, 2)
+	release := make(chan struct{})
+	r.Add(func(int) { <-release })
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	var ran atomic.Int32
+	for i := 0; i < 5; i++ {
+		r.Add(func(int) {
+			time.Sleep(2 * time.Millisecond)
+			ran.Add(1)
+		})
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatalf("Flush() = %v, want nil", err)
+	}
+	if m := r.Metrics(); m.Pending != 0 {
+		t.Fatalf("Metrics().Pending = %d after Flush, want 0", m.Pending)
+	}
+	if got := ran.Load(); got < 4 {
+		t.Fatalf("%d tasks of the batch done after Flush, want at least the 4 before the last", got)
+	}
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := ran.Load(); got != 5 {
+		t.Fatalf("%d tasks of the batch ran, want 5", got)
+	}
+}
+
+func TestFlushRunEnds(t *testing.T) {
+	r := New(20*time.Millisecond, 1)
+	r.Add(blocker(r), func(int) {})
+	go r.Start()
+	if err := r.Flush(); err != ErrTimeout {
+		t.Fatalf("Flush() = %v, want %v once the run timed out", err, ErrTimeout)
+	}
+}
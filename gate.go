@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+// memoryPoll is how often a worker parked by the memory gate checks the
+// heap again.
+const memoryPoll = 10 * time.Millisecond
+
+// waitForMemory parks the calling worker while the heap is above the memory
+// gate and reports whether the run is still in progress.
+func (r *Runner) waitForMemory(done <-chan struct{}) bool {
+	var ms runtime.MemStats
+	for {
+		runtime.ReadMemStats(&ms)
+		if ms.HeapAlloc <= r.maxHeap {
+			return true
+		}
+		select {
+		case <-done:
+			return false
+		case <-time.After(memoryPoll):
+		}
+	}
+}
+
+// jitter sleeps for a random duration below the start jitter and reports
+// whether the run is still in progress afterwards.
+func (r *Runner) jitter(done <-chan struct{}) bool {
+	return r.sleep(done, time.Duration(rand.Int63n(int64(r.startJitter))))
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryGateThrottles(t *testing.T) {
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	r := NewWithOptions(5*time.Second, 1, WithMemoryGate(ms.HeapAlloc+32<<20))
+	var (
+		mu       sync.Mutex
+		hold     []byte
+		released time.Time
+		started  time.Time
+	)
+	r.Add(func(int) {
+		mu.Lock()
+		hold = make([]byte, 64<<20)
+		mu.Unlock()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			mu.Lock()
+			hold, released = nil, time.Now()
+			mu.Unlock()
+			runtime.GC()
+		}()
+	})
+	r.Add(func(int) {
+		mu.Lock()
+		started = time.Now()
+		mu.Unlock()
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hold != nil || started.Before(released) {
+		t.Fatal("the second task started while the heap was over the gate")
+	}
+}
+
+func TestStartJitterSpreadsStarts(t *testing.T) {
+	r := NewWithOptions(5*time.Second, 8, WithStartJitter(50*time.Millisecond))
+	var mu sync.Mutex
+	var starts []time.Time
+	for i := 0; i < 8; i++ {
+		r.Add(func(int) {
+			mu.Lock()
+			starts = append(starts, time.Now())
+			mu.Unlock()
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	first, last := starts[0], starts[0]
+	for _, s := range starts {
+		if s.Before(first) {
+			first = s
+		}
+		if s.After(last) {
+			last = s
+		}
+	}
+	if spread := last.Sub(first); spread < 5*time.Millisecond {
+		t.Fatalf("starts spread over %v, want them spread out", spread)
+	}
+}
+
+func TestStartJitterRespectsTimeout(t *testing.T) {
+	r := NewWithOptions(20*time.Millisecond, 1, WithStartJitter(time.Hour))
+	var ran atomic.Bool
+	r.Add(func(int) { ran.Store(true) })
+	start := time.Now()
+	if err := r.Start(); err != ErrTimeout {
+		t.Fatalf("Start() = %v, want %v", err, ErrTimeout)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Start() took %v, want it to return on the timeout", d)
+	}
+	// the worker gives up its jitter once the run has ended
+	deadline := time.Now().Add(time.Second)
+	for r.GoroutineCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if r.GoroutineCount() > 0 || ran.Load() {
+		t.Fatal("the worker slept past the end of the run")
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.busy[0] != nil || r.queue.len() != 1 {
+		t.Fatal("the task taken by the worker was not given back")
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/alob-mtc/runner
 
-go 1.14
+go 1.21
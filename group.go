@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"context"
+	"sync"
+)
+
+// Group is an errgroup-like front end to a Runner, easing the migration of
+// code written against golang.org/x/sync/errgroup. Functions passed to Go are
+// queued on the runner and executed by its worker pool when Wait is called,
+// so the pool size plays the role of errgroup's SetLimit.
+type Group struct {
+	r      *Runner
+	cancel context.CancelFunc
+
+	errOnce sync.Once
+	err     error
+}
+
+// AsErrGroup returns a Group backed by r and a context derived from ctx.
+// The derived context is canceled the first time a function passed to Go
+// returns a non-nil error, when the runner times out or is interrupted, or
+// when Wait returns, whichever occurs first.
+func (r *Runner) AsErrGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{r: r, cancel: cancel}, ctx
+}
+
+// Go queues f on the runner.
+func (g *Group) Go(f func() error) {
+	g.r.AddFallible(func(int) error {
+		err := f()
+		if err != nil {
+			g.fail(err)
+		}
+		return err
+	})
+}
+
+// Wait runs the queued functions and blocks until they have all returned
+// or the runner stops, then returns the first error encountered, if any.
+func (g *Group) Wait() error {
+	if err := g.r.Start(); err != nil {
+		g.fail(err)
+	}
+	g.cancel()
+	return g.err
+}
+
+// fail records err if it is the first error and cancels the context.
+func (g *Group) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
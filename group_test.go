@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupWaitSucceeds(t *testing.T) {
+	g, ctx := New(time.Second, 2).AsErrGroup(context.Background())
+	var n atomic.Int32
+	for i := 0; i < 5; i++ {
+		g.Go(func() error {
+			n.Add(1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if n.Load() != 5 {
+		t.Fatalf("%d functions ran, want 5", n.Load())
+	}
+	if ctx.Err() == nil {
+		t.Fatal("context not canceled once Wait returned")
+	}
+}
+
+func TestGroupFirstErrorCancels(t *testing.T) {
+	errBoom := errors.New("boom")
+	g, ctx := New(time.Second, 2).AsErrGroup(context.Background())
+	g.Go(func() error {
+		return errBoom
+	})
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return errors.New("context not canceled")
+		}
+	})
+	if err := g.Wait(); err != errBoom {
+		t.Fatalf("Wait() = %v, want %v", err, errBoom)
+	}
+}
+
+func TestGroupLimit(t *testing.T) {
+	g, _ := New(time.Second, 2).AsErrGroup(context.Background())
+	var cur, peak atomic.Int32
+	for i := 0; i < 8; i++ {
+		g.Go(func() error {
+			n := cur.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			cur.Add(-1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("%d functions ran at once, want at most 2", p)
+	}
+}
+
+func TestGroupTimeoutCancels(t *testing.T) {
+	g, ctx := New(20*time.Millisecond, 1).AsErrGroup(context.Background())
+	g.Go(func() error {
+		<-ctx.Done()
+		return nil
+	})
+	if err := g.Wait(); err != ErrTimeout {
+		t.Fatalf("Wait() = %v, want %v", err, ErrTimeout)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("context not canceled on timeout")
+	}
+}
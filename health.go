@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// ErrDependencyUnhealthy is returned when the health check set with
+// WithHealthCheck keeps failing for longer than the grace period.
+var ErrDependencyUnhealthy = errors.New("dependency unhealthy")
+
+// probeHealth runs the health check, holding back dispatch while it fails
+// and resuming it once it passes again. It reports whether the dependency
+// is healthy.
+func (r *Runner) probeHealth() bool {
+	healthy := r.healthCheck()
+	r.m.Lock()
+	changed := healthy == r.unhealthy
+	r.unhealthy = !healthy
+	if healthy && changed {
+		r.cond.Broadcast()
+		r.respawn()
+	}
+	r.m.Unlock()
+	switch {
+	case !changed:
+	case healthy:
+		r.log("dependency healthy again, dispatch resumed")
+	default:
+		r.log("dependency unhealthy, dispatch held back")
+	}
+	return healthy
+}
+
+// checkHealth polls the health check every interval until done is closed,
+// healthy being the result of the probe made before dispatch started. The
+// run ends with ErrDependencyUnhealthy once the check has failed for longer
+// than the grace period.
+func (r *Runner) checkHealth(done <-chan struct{}, healthy bool) {
+	ticker := time.NewTicker(r.healthInterval)
+	defer ticker.Stop()
+	var unhealthySince time.Time
+	for {
+		if healthy {
+			unhealthySince = time.Time{}
+		} else {
+			if unhealthySince.IsZero() {
+				unhealthySince = time.Now()
+			}
+			if r.healthGrace > 0 && time.Since(unhealthySince) > r.healthGrace {
+				r.log("dependency unhealthy for too long", slog.Duration("grace", r.healthGrace))
+				r.end(ErrDependencyUnhealthy)
+				return
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+		healthy = r.probeHealth()
+	}
+}
@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckPausesDispatch(t *testing.T) {
+	var healthy, ran atomic.Bool
+	r := NewWithOptions(time.Second, 1, WithHealthCheck(healthy.Load, 5*time.Millisecond))
+	r.Add(func(int) { ran.Store(true) })
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	time.Sleep(50 * time.Millisecond)
+	if ran.Load() {
+		t.Fatal("a task ran while the dependency was unhealthy")
+	}
+	healthy.Store(true)
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if !ran.Load() {
+		t.Fatal("the task did not run once the dependency was healthy again")
+	}
+}
+
+func TestHealthGrace(t *testing.T) {
+	var ran atomic.Bool
+	r := NewWithOptions(time.Second, 2,
+		WithHealthCheck(func() bool { return false }, 5*time.Millisecond),
+		WithHealthGrace(30*time.Millisecond),
+	)
+	for i := 0; i < 4; i++ {
+		r.Add(func(int) { ran.Store(true) })
+	}
+	if err := r.Start(); err != ErrDependencyUnhealthy {
+		t.Fatalf("Start() = %v, want %v", err, ErrDependencyUnhealthy)
+	}
+	if ran.Load() {
+		t.Fatal("a task ran against a dependency that never was healthy")
+	}
+}
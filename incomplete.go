@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrIncompleteRun matches the IncompleteRunError returned under
+// WithRequireAllStarted.
+var ErrIncompleteRun = errors.New("run incomplete")
+
+// IncompleteRunError is returned under WithRequireAllStarted when the run
+// times out with tasks that never started. It matches both ErrIncompleteRun
+// and the error the run timed out with, ErrTimeout or ErrDeadline.
+type IncompleteRunError struct {
+	// Err is the error the run timed out with.
+	Err error
+
+	// Unstarted holds the indices of the tasks that never started, in
+	// ascending order.
+	Unstarted []int
+}
+
+func (e *IncompleteRunError) Error() string {
+	return fmt.Sprintf("%v: %v, %d tasks never started: %v", ErrIncompleteRun, e.Err, len(e.Unstarted), e.Unstarted)
+}
+
+// Unwrap returns ErrIncompleteRun and the error the run timed out with.
+func (e *IncompleteRunError) Unwrap() []error {
+	return []error{ErrIncompleteRun, e.Err}
+}
+
+// incomplete turns err into an IncompleteRunError when the run timed out
+// with tasks that never started and WithRequireAllStarted is set.
+func (r *Runner) incomplete(err error) error {
+	if !r.requireAllStarted || (err != ErrTimeout && err != ErrDeadline) {
+		return err
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	var unstarted []int
+	for _, t := range r.queue.pending() {
+		unstarted = append(unstarted, t.index)
+	}
+	for _, stage := range r.held {
+		for _, t := range stage {
+			unstarted = append(unstarted, t.index)
+		}
+	}
+	for _, s := range r.sequences {
+		for _, t := range s.waiting {
+			unstarted = append(unstarted, t.index)
+		}
+	}
+	if len(unstarted) == 0 {
+		return err
+	}
+	sort.Ints(unstarted)
+	return &IncompleteRunError{Err: err, Unstarted: unstarted}
+}
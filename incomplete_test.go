@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRequireAllStarted(t *testing.T) {
+	r := NewWithOptions(20*time.Millisecond, 1, WithRequireAllStarted())
+	r.Add(blocker(r))
+	r.Add(func(int) {}, func(int) {})
+	err := r.Start()
+	if !errors.Is(err, ErrIncompleteRun) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Start() = %v, want an error matching %v and %v", err, ErrIncompleteRun, ErrTimeout)
+	}
+	var incomplete *IncompleteRunError
+	if !errors.As(err, &incomplete) {
+		t.Fatalf("Start() = %T, want *IncompleteRunError", err)
+	}
+	if got := incomplete.Unstarted; len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("Unstarted = %v, want [1 2]", got)
+	}
+}
+
+func TestRequireAllStartedAllStarted(t *testing.T) {
+	r := NewWithOptions(20*time.Millisecond, 1, WithRequireAllStarted())
+	r.Add(blocker(r))
+	if err := r.Start(); err != ErrTimeout {
+		t.Fatalf("Start() = %v, want plain %v when every task started", err, ErrTimeout)
+	}
+}
@@ -0,0 +1,51 @@
+package runner
+
+import "time"
+
+// RunnerMetrics is a snapshot of the progress of a run.
+type RunnerMetrics struct {
+	// Registered is the number of tasks registered so far.
+	Registered int
+
+	// Pending is the number of tasks waiting for a worker, including
+	// those held back by a later stage or a sequential group.
+	Pending int
+
+	// Running is the number of tasks taken by a worker that have not
+	// finished yet.
+	Running int
+
+	// Succeeded and Failed count the tasks that have run, by outcome.
+	Succeeded, Failed int
+
+	// Elapsed is the time since the run started, zero before Start.
+	Elapsed time.Duration
+}
+
+// Metrics returns a snapshot of the progress of the current or last run.
+func (r *Runner) Metrics() RunnerMetrics {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.metrics()
+}
+
+// metrics returns a snapshot of the progress of the run, r.m must be held.
+func (r *Runner) metrics() RunnerMetrics {
+	m := RunnerMetrics{
+		Registered: len(r.tasks),
+		Pending:    r.backlog(),
+		Succeeded:  r.succeeded,
+		Failed:     r.failed,
+	}
+	m.Running = len(r.tasks) - r.finished - m.Pending
+	if !r.timeline.DispatchStart.IsZero() {
+		m.Elapsed = time.Since(r.timeline.DispatchStart)
+	}
+	return m
+}
+
+// backlog returns the number of tasks that have not started yet, queued or
+// held back by a stage or a sequential group, r.m must be held.
+func (r *Runner) backlog() int {
+	return r.queue.len() + r.heldCount + r.sequenced
+}
@@ -0,0 +1,477 @@
+package runner
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Option configures a Runner created with NewWithOptions.
+type Option func(*Runner)
+
+// WithTotalWorkBudget caps the cumulative duration of the completed tasks.
+// Once the sum exceeds d no new task is started, in-flight tasks are left to
+// finish and Start returns ErrWorkBudgetExceeded.
+func WithTotalWorkBudget(d time.Duration) Option {
+	return func(r *Runner) {
+		r.workBudget = d
+	}
+}
+
+// WithSlog emits the runner lifecycle events (run start and end, task start
+// and end) as structured records on logger. Task records carry the task
+// index, the worker id, the outcome and the duration as attributes. Without
+// a logger the runner stays silent, but for the panics it recovers, see
+// WithPanicHandler.
+func WithSlog(logger *slog.Logger) Option {
+	return func(r *Runner) {
+		r.logger = logger
+	}
+}
+
+// WithQuorum ends the run successfully as soon as k tasks have completed
+// without error. Tasks still queued at that point are not started. When
+// too many tasks failed for k successes to remain possible, Start returns
+// ErrQuorumNotMet.
+func WithQuorum(k int) Option {
+	return func(r *Runner) {
+		r.quorum = k
+	}
+}
+
+// WithCompletionPredicate ends the run as soon as fn returns true. fn is
+// called with the metrics of the run after each task finishes, one call at
+// a time. Start then returns nil, whether or not tasks are left.
+func WithCompletionPredicate(fn func(RunnerMetrics) bool) Option {
+	return func(r *Runner) {
+		r.completed = fn
+	}
+}
+
+// WithTaskFilter passes each task through fn as a worker takes it, before
+// it runs. fn is called with the index of the task and its function, and
+// returns the function to run instead, which may be the one it was handed.
+// Returning nil skips the task, which is then recorded with ErrTaskSkipped.
+func WithTaskFilter(fn func(index int, task func(int)) func(int)) Option {
+	return func(r *Runner) {
+		r.filter = fn
+	}
+}
+
+// WithPersistentQueue saves the tasks added with AddDescriptor to q until
+// they have run, see Recover.
+func WithPersistentQueue(q PersistentQueue) Option {
+	return func(r *Runner) {
+		r.persist = q
+	}
+}
+
+// WithConcurrentFailureLimit ends the run with ErrConcurrentFailures once n
+// tasks have failed while running at the same time, which likely signals a
+// systemic problem rather than a bad task. The tasks still pending are not
+// started and the context of the running context-aware tasks is canceled.
+func WithConcurrentFailureLimit(n int) Option {
+	return func(r *Runner) {
+		r.failureLimit = n
+	}
+}
+
+// WithResultRingBuffer retains only the results of the last n tasks to
+// finish, for runs too large to keep every result in memory. Results then
+// returns the retained results and DroppedResults counts the others.
+// Features relying on the results of earlier tasks, such as WaitTask, no
+// longer see their values.
+func WithResultRingBuffer(n int) Option {
+	return func(r *Runner) {
+		r.ringSize = n
+	}
+}
+
+// WithCheckpoint saves the indices of the tasks completed successfully to
+// store every interval while the run is in progress, and once more when it
+// ends, so that a resumed run can skip them with SkipCompleted.
+func WithCheckpoint(store CheckpointStore, interval time.Duration) Option {
+	return func(r *Runner) {
+		r.checkpointStore = store
+		r.checkpointInterval = interval
+	}
+}
+
+// SkipCompleted skips the tasks whose indices are in store when the run
+// starts, as saved by an earlier run with WithCheckpoint. The resumed run
+// must register the same tasks in the same order. Start returns the error
+// of loading the store, if any, without running anything.
+func SkipCompleted(store CheckpointStore) Option {
+	return func(r *Runner) {
+		r.skipStore = store
+	}
+}
+
+// WithAllocProfiling records in the result of each task the memory
+// allocated while it ran. The figures come from runtime.ReadMemStats, which
+// stops the world and is expensive, and they are process-wide: they include
+// whatever the other goroutines allocated meanwhile, so they are only
+// approximate when tasks run concurrently.
+func WithAllocProfiling() Option {
+	return func(r *Runner) {
+		r.allocProfiling = true
+	}
+}
+
+// WithGroupScorer sets fn to score the groups of tasks added with AddGroup.
+// fn is called by GroupScore with the name of each group and the results of
+// its members that have run, and may weigh them as it sees fit.
+func WithGroupScorer(fn func(name string, results []TaskResult) float64) Option {
+	return func(r *Runner) {
+		r.groupScorer = fn
+	}
+}
+
+// WithPanicHandler hands the panics recovered from tasks to fn along with
+// the index of the task and the stack of the panicking goroutine, in place
+// of logging them. The task then counts as failed with an error wrapping
+// ErrTaskPanicked. fn may re-panic to crash the process after all. Without
+// a handler a panic is logged, on the logger set with WithSlog or else the
+// default slog logger, and recorded the same way.
+func WithPanicHandler(fn func(taskIndex int, recovered any, stack []byte)) Option {
+	return func(r *Runner) {
+		r.panicHandler = fn
+	}
+}
+
+// WithoutPanicRecovery lets a panicking task crash the process rather than
+// recovering the panic, unless a panic handler is set or WithRetryOnPanic
+// is used.
+func WithoutPanicRecovery() Option {
+	return func(r *Runner) {
+		r.noRecover = true
+	}
+}
+
+// WithTimeout sets the time the run has to finish, counted from the call
+// to Start, overriding the duration passed to NewWithOptions. Start returns
+// ErrTimeout when it runs out.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.timeoutDuration = d
+	}
+}
+
+// WithDeadline sets an absolute time by which the run has to finish. It
+// coexists with the timeout, whichever comes first ends the run. Start
+// returns ErrDeadline when the deadline is the one that passed.
+func WithDeadline(t time.Time) Option {
+	return func(r *Runner) {
+		r.deadline = t
+	}
+}
+
+// WithResultCache lets keyed tasks, registered with AddKeyed, be served from
+// cache instead of running when cache holds their key. The cache is
+// supplied and owned by the caller, so it can outlive the runner.
+func WithResultCache(cache Cache) Option {
+	return func(r *Runner) {
+		r.cache = cache
+	}
+}
+
+// WithSoftDeadline stops starting tasks once deadline passes, but gives the
+// tasks running by then up to grace to finish. Start returns ErrTimeout as
+// soon as they have all finished, or once grace is over if they have not,
+// abandoning them. It returns nil if no task was left by the deadline.
+func WithSoftDeadline(deadline time.Time, grace time.Duration) Option {
+	return func(r *Runner) {
+		r.softDeadline = deadline
+		r.softGrace = grace
+	}
+}
+
+// WithRequireAllStarted makes a run that times out with tasks that never
+// started fail with an IncompleteRunError listing them, instead of plain
+// ErrTimeout or ErrDeadline, which it still matches with errors.Is.
+func WithRequireAllStarted() Option {
+	return func(r *Runner) {
+		r.requireAllStarted = true
+	}
+}
+
+// WithTaskTimeoutSoft keeps the tasks that ran past their own timeout, see
+// AddWithTimeout, from counting as failed. Their result still carries
+// ErrTaskTimeout, but they count towards neither the failures of the run,
+// as reported and as limited by WithConcurrentFailureLimit, nor the
+// successes the quorum waits for.
+func WithTaskTimeoutSoft() Option {
+	return func(r *Runner) {
+		r.softTaskTimeout = true
+	}
+}
+
+// WithTimeoutDrain makes the runner finish the tasks already running when
+// the timeout or the deadline passes. No new task is started, and Start
+// returns ErrTimeout or ErrDeadline only once the running tasks are done.
+func WithTimeoutDrain() Option {
+	return func(r *Runner) {
+		r.timeoutDrain = true
+	}
+}
+
+// WithMemoryGate holds back the dispatch of new tasks while the heap in use
+// is above maxHeapBytes. Workers check runtime.ReadMemStats before taking a
+// task and park until the heap shrinks or the run ends. This is a coarse
+// form of backpressure: ReadMemStats briefly stops the world, and tasks
+// already running are not affected.
+func WithMemoryGate(maxHeapBytes uint64) Option {
+	return func(r *Runner) {
+		r.maxHeap = maxHeapBytes
+	}
+}
+
+// WithStartJitter makes workers wait a random duration in [0, max) before
+// each task, spreading out the load tasks put on a shared downstream. The
+// wait is cut short when the run ends, in which case the task is not run.
+func WithStartJitter(max time.Duration) Option {
+	return func(r *Runner) {
+		r.startJitter = max
+	}
+}
+
+// WithSource makes the workers pull tasks from src whenever the queue is
+// empty, until src is exhausted. This suits streaming or unbounded
+// producers, whose run is then bounded by the timeout. The run completes
+// once src is exhausted and every task has finished.
+func WithSource(src TaskSource) Option {
+	return func(r *Runner) {
+		r.source = src
+	}
+}
+
+// WithPauseSignal lets operators throttle a running job from outside: each
+// time sig is received the dispatch of tasks is paused if it was running
+// and resumed if it was paused, as with Pause and Resume.
+func WithPauseSignal(sig os.Signal) Option {
+	return func(r *Runner) {
+		r.OnSignal(sig, r.togglePause)
+	}
+}
+
+// WithRetryPolicy retries the tasks that return an error according to p.
+// Tasks are retried on the same worker, after the delay given by the policy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(r *Runner) {
+		r.retry = p
+	}
+}
+
+// WithRetryBudget caps the number of retries across all the tasks of a
+// run to n. Once n retries have been made, failed tasks are no longer
+// retried, which keeps a widespread failure from turning into a retry
+// storm.
+func WithRetryBudget(n int) Option {
+	return func(r *Runner) {
+		r.retryBudget = n
+	}
+}
+
+// WithRetryClassifier consults fn before retrying a failed task: only the
+// errors for which fn returns true are retried, the others fail the task
+// right away. Errors wrapping ErrPermanent are never retried.
+func WithRetryClassifier(fn func(err error) bool) Option {
+	return func(r *Runner) {
+		r.retryableErr = fn
+	}
+}
+
+// WithRetryOnPanic recovers the panics raised by tasks and retries the
+// panicking tasks according to the retry policy, like the tasks returning
+// an error. A task that still panics on its last attempt counts as failed
+// with an error wrapping ErrTaskPanicked and the last panic. A panic
+// handler, if any, sees every panic.
+func WithRetryOnPanic() Option {
+	return func(r *Runner) {
+		r.retryOnPanic = true
+	}
+}
+
+// WithHealthCheck calls fn before the first task is dispatched, then polls
+// it every interval while the run is in progress. While fn returns false no
+// new task is started, dispatch resumes when it returns true again. See
+// WithHealthGrace to give up on a dependency that stays unhealthy.
+func WithHealthCheck(fn func() bool, interval time.Duration) Option {
+	return func(r *Runner) {
+		r.healthCheck = fn
+		r.healthInterval = interval
+	}
+}
+
+// WithHealthGrace ends the run with ErrDependencyUnhealthy when the health
+// check set with WithHealthCheck keeps failing for longer than d. Without
+// it the run waits for the dependency until it times out.
+func WithHealthGrace(d time.Duration) Option {
+	return func(r *Runner) {
+		r.healthGrace = d
+	}
+}
+
+// WithTimeSlice sets the time a task added with AddSliced runs before it
+// yields to the pending tasks, 10ms by default.
+func WithTimeSlice(d time.Duration) Option {
+	return func(r *Runner) {
+		r.timeSlice = d
+	}
+}
+
+// WithWorkerInit calls fn once for each worker, as it starts, to build a
+// value the worker keeps for the whole life of the runner, such as a
+// buffer or a client reused across tasks. Tasks added with
+// AddWithWorkerValue are handed the value of the worker running them, the
+// others can get at it with WorkerValue. A worker respawned after its idle
+// timeout keeps the value of its predecessor.
+func WithWorkerInit(fn func(workerID int) any) Option {
+	return func(r *Runner) {
+		r.workerInit = fn
+	}
+}
+
+// WithChannelBuffers sets the buffers of the channels the workers report
+// to the master goroutine on: complete for the finished tasks and the
+// exited workers, main for the end of the run. Each task still gets
+// recorded and each worker exit still counted, in the order they were
+// sent, so the run ends at the same point; a buffer only lets a worker
+// move on to its next task before the master has recorded the previous
+// one, which cuts the contention under many short tasks but means that
+// hooks and Metrics may lag the workers by up to complete tasks. The run
+// ends once, so main only needs 1, which is the default and the minimum;
+// complete is unbuffered by default.
+func WithChannelBuffers(complete, main int) Option {
+	return func(r *Runner) {
+		r.complete = make(chan completion, max(complete, 0))
+		r.mainBuffer = max(main, 1)
+		r.completeMain = make(chan error, r.mainBuffer)
+	}
+}
+
+// WithoutSignalHandling keeps the runner from listening to operating
+// system signals, for applications that own the signal handling: Start
+// does not register with the Notifier, interrupts no longer end the run and
+// the handlers registered with OnSignal are never called. The run can
+// still be ended with Stop or Abort.
+func WithoutSignalHandling() Option {
+	return func(r *Runner) {
+		r.ignoreSignals = true
+	}
+}
+
+// WithBurstWorker keeps a standby worker on top of the regular ones, which
+// only runs while more than activateDepth tasks are queued: it is spawned
+// when the queue grows past that depth and exits once it has drained back
+// to it. The standby worker has the id just after the regular workers, as
+// seen by OnWorkerStart, OnWorkerExit and in TaskResult.Worker. It has no
+// effect under WithSynchronous.
+func WithBurstWorker(activateDepth int) Option {
+	return func(r *Runner) {
+		if !r.burstWorker {
+			r.alive = append(r.alive, false)
+			r.busy = append(r.busy, nil)
+		}
+		r.burstWorker, r.burstDepth = true, activateDepth
+	}
+}
+
+// WithName names the runner. Every event it logs, see WithSlog, carries
+// the name as its "runner" attribute, so that the records of several
+// runners sharing a logger can be told apart.
+func WithName(name string) Option {
+	return func(r *Runner) {
+		r.name = name
+	}
+}
+
+// WithPreemption lets a Critical task added while every worker is busy with
+// tasks of lower priority take the worker of a context-aware one: the
+// context of the lowest-priority such task is canceled and, once it has
+// returned, the task is queued again to run from the start. Tasks that are
+// not context-aware are never preempted. It has no effect under
+// WithSynchronous.
+func WithPreemption() Option {
+	return func(r *Runner) {
+		r.preemption = true
+	}
+}
+
+// WithNotifier relays the operating system signals through n instead of
+// the os/signal package, which lets tests deliver signals to the runner.
+func WithNotifier(n Notifier) Option {
+	return func(r *Runner) {
+		r.notifier = n
+	}
+}
+
+// WithDefaultTask runs fn in place of the slots registered with Reserve
+// that were not filled by the time they run.
+func WithDefaultTask(fn func(int)) Option {
+	return func(r *Runner) {
+		r.defaultTask = fn
+	}
+}
+
+// WithReplayTrace forces the dispatch decisions recorded in tr on the run:
+// the tasks are handed out in the order of the trace, each to the worker
+// that ran it, so that the run can be replayed to debug a failure that
+// depends on the scheduling. The run must register the same tasks in the
+// same order and have as many workers as the recorded one. Once the trace
+// is exhausted, the tasks are dispatched as usual. It has no effect under
+// WithSynchronous, which always runs the tasks in registration order.
+func WithReplayTrace(tr *Trace) Option {
+	return func(r *Runner) {
+		r.replay = tr
+	}
+}
+
+// WithRampUp brings the workers online gradually at the beginning of the
+// run, step apart, rather than all at once, to spare a cold downstream.
+// Once they are all up they behave as usual.
+func WithRampUp(step time.Duration) Option {
+	return func(r *Runner) {
+		r.rampUp = step
+	}
+}
+
+// WithDoubleInterruptForce makes an interrupt drain the run gracefully: no
+// new task starts and Start returns ErrInterrupt once the running tasks
+// have finished. A second interrupt within window of the first forces the
+// run to end at once, as Abort does, and Start then returns ErrAborted.
+func WithDoubleInterruptForce(window time.Duration) Option {
+	return func(r *Runner) {
+		r.forceWindow = window
+	}
+}
+
+// WithIdleTimeout lets a worker that found no task for d exit. Workers are
+// spawned again, up to the configured number, as new tasks are added. This
+// keeps long-lived runners that get work in bursts from holding on to idle
+// goroutines.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.idleTimeout = d
+	}
+}
+
+// WithYieldBetweenTasks makes workers call runtime.Gosched after each task,
+// improving the fairness of CPU-bound tasks towards the other goroutines of
+// a latency-sensitive process.
+func WithYieldBetweenTasks() Option {
+	return func(r *Runner) {
+		r.yield = true
+	}
+}
+
+// WithSynchronous runs the tasks one at a time on the goroutine calling
+// Start, in registration order, without spawning any worker. The timeout
+// and the deadline are checked between tasks, so a task running late is
+// not interrupted. Tasks run as worker 0. This makes runs deterministic,
+// which is meant for testing and debugging task logic.
+func WithSynchronous() Option {
+	return func(r *Runner) {
+		r.synchronous = true
+	}
+}
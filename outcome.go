@@ -0,0 +1,63 @@
+package runner
+
+// Outcome is the terminal reason of a run.
+type Outcome int
+
+// The outcomes of a run.
+const (
+	// Unfinished is the outcome until the run has ended.
+	Unfinished Outcome = iota
+	// Completed means every task ran, or the run ended early on success.
+	Completed
+	// TimedOut means the timeout or the deadline passed.
+	TimedOut
+	// Interrupted means an interrupt or termination signal was received
+	// from the OS.
+	Interrupted
+	// Stopped means the run was ended by Stop or Abort.
+	Stopped
+	// Failed means the run ended on any other error.
+	Failed
+)
+
+// String returns the name of the outcome.
+func (o Outcome) String() string {
+	switch o {
+	case Unfinished:
+		return "unfinished"
+	case Completed:
+		return "completed"
+	case TimedOut:
+		return "timed out"
+	case Interrupted:
+		return "interrupted"
+	case Stopped:
+		return "stopped"
+	case Failed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// outcomeOf maps the error a run ended with to its outcome.
+func outcomeOf(err error) Outcome {
+	switch err {
+	case nil:
+		return Completed
+	case ErrTimeout, ErrDeadline:
+		return TimedOut
+	case ErrInterrupt:
+		return Interrupted
+	case ErrStopped, ErrAborted:
+		return Stopped
+	}
+	return Failed
+}
+
+// Outcome returns the terminal reason of the last run, or Unfinished while
+// no run has ended.
+func (r *Runner) Outcome() Outcome {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.outcome
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T) *Runner
+		want Outcome
+	}{
+		{"completed", func(t *testing.T) *Runner {
+			r := New(time.Second, 1)
+			r.Add(func(int) {})
+			r.Start()
+			return r
+		}, Completed},
+		{"timed out", func(t *testing.T) *Runner {
+			r := New(10*time.Millisecond, 1)
+			r.Add(blocker(r))
+			r.Start()
+			return r
+		}, TimedOut},
+		{"interrupted", func(t *testing.T) *Runner {
+			n := &fakeNotifier{}
+			r := NewWithOptions(time.Second, 1, WithNotifier(n))
+			r.Add(blocker(r))
+			go n.send(t, os.Interrupt)
+			r.Start()
+			return r
+		}, Interrupted},
+		{"stopped", func(t *testing.T) *Runner {
+			r := New(time.Second, 1)
+			r.Add(func(int) { r.Stop() })
+			r.Start()
+			return r
+		}, Stopped},
+		{"failed", func(t *testing.T) *Runner {
+			r := NewWithOptions(time.Second, 1, WithQuorum(1))
+			r.AddFallible(func(int) error { return errors.New("boom") })
+			r.Start()
+			return r
+		}, Failed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.run(t).Outcome(); got != tt.want {
+				t.Fatalf("Outcome() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomeUnfinished(t *testing.T) {
+	if got := New(time.Second, 1).Outcome(); got != Unfinished {
+		t.Fatalf("Outcome() = %v before the run, want %v", got, Unfinished)
+	}
+}
@@ -0,0 +1,181 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// ErrUnknownFactory is returned for a task descriptor whose name has no
+// factory registered with RegisterFactory.
+var ErrUnknownFactory = errors.New("no factory registered for task")
+
+// TaskDescriptor is the serializable form of a task, for the tasks that
+// must survive a restart of the process. The function of the task is built
+// from the descriptor by the factory registered under its name.
+type TaskDescriptor struct {
+	// ID identifies the descriptor in the persistent queue, it must be
+	// unique.
+	ID string
+
+	// Name selects the factory that builds the task.
+	Name string
+
+	// Payload is handed to the factory, it holds the arguments of the
+	// task in whatever encoding the factory expects.
+	Payload []byte
+}
+
+// TaskFactory builds the function of a task from the payload of its
+// descriptor.
+type TaskFactory func(payload []byte) (func(int), error)
+
+// PersistentQueue stores the descriptors of the tasks that have not run
+// yet. Implementations must be safe for concurrent use.
+type PersistentQueue interface {
+	// Save stores d, replacing any descriptor with the same ID.
+	Save(d TaskDescriptor) error
+
+	// Remove deletes the descriptor with the given ID, if any.
+	Remove(id string) error
+
+	// Load returns the stored descriptors in the order they were saved.
+	Load() ([]TaskDescriptor, error)
+}
+
+// RegisterFactory registers f to build the tasks described under name. It
+// must be called before the descriptors using name are added or recovered.
+func (r *Runner) RegisterFactory(name string, f TaskFactory) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.factories == nil {
+		r.factories = make(map[string]TaskFactory)
+	}
+	r.factories[name] = f
+}
+
+// AddDescriptor attaches the task described by d. The descriptor is saved
+// to the persistent queue set with WithPersistentQueue, if any, and removed
+// from it once the task has run, so that Recover brings back the tasks a
+// crash left unfinished.
+func (r *Runner) AddDescriptor(d TaskDescriptor) error {
+	fn, err := r.resolve(d)
+	if err != nil {
+		return err
+	}
+	if r.persist != nil {
+		if err := r.persist.Save(d); err != nil {
+			return fmt.Errorf("saving task %q: %w", d.ID, err)
+		}
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.push(fn).more().descriptor = d.ID
+	return nil
+}
+
+// Recover attaches the tasks left in the persistent queue by a previous
+// process and returns how many there were. It is meant to be called on
+// startup, before Start. A descriptor that cannot be resolved stops the
+// recovery with an error, the tasks recovered until then stay attached.
+func (r *Runner) Recover() (int, error) {
+	if r.persist == nil {
+		return 0, nil
+	}
+	descriptors, err := r.persist.Load()
+	if err != nil {
+		return 0, fmt.Errorf("loading tasks: %w", err)
+	}
+	for i, d := range descriptors {
+		fn, err := r.resolve(d)
+		if err != nil {
+			return i, err
+		}
+		r.m.Lock()
+		r.push(fn).more().descriptor = d.ID
+		r.m.Unlock()
+	}
+	return len(descriptors), nil
+}
+
+// resolve builds the function of the task described by d.
+func (r *Runner) resolve(d TaskDescriptor) (func(int), error) {
+	r.m.Lock()
+	f, ok := r.factories[d.Name]
+	r.m.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFactory, d.Name)
+	}
+	fn, err := f(d.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("building task %q: %w", d.ID, err)
+	}
+	return fn, nil
+}
+
+// forget removes the descriptor of t from the persistent queue once t has
+// run.
+func (r *Runner) forget(t *task) {
+	id := t.ext().descriptor
+	if r.persist == nil || id == "" {
+		return
+	}
+	if err := r.persist.Remove(id); err != nil {
+		r.log("removing persisted task failed", slog.Int("task", t.index),
+			slog.String("id", id), slog.String("error", err.Error()))
+	}
+}
+
+// MemoryQueue is a PersistentQueue held in memory. It does not survive the
+// process and is meant for testing and as a reference implementation.
+type MemoryQueue struct {
+	m     sync.Mutex
+	order []string
+	byID  map[string]TaskDescriptor
+}
+
+// NewMemoryQueue returns an empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{byID: make(map[string]TaskDescriptor)}
+}
+
+// Save implements PersistentQueue.
+func (q *MemoryQueue) Save(d TaskDescriptor) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+	if _, ok := q.byID[d.ID]; !ok {
+		q.order = append(q.order, d.ID)
+	}
+	d.Payload = append([]byte(nil), d.Payload...)
+	q.byID[d.ID] = d
+	return nil
+}
+
+// Remove implements PersistentQueue.
+func (q *MemoryQueue) Remove(id string) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+	if _, ok := q.byID[id]; !ok {
+		return nil
+	}
+	delete(q.byID, id)
+	for i, o := range q.order {
+		if o == id {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Load implements PersistentQueue.
+func (q *MemoryQueue) Load() ([]TaskDescriptor, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	descriptors := make([]TaskDescriptor, 0, len(q.order))
+	for _, id := range q.order {
+		descriptors = append(descriptors, q.byID[id])
+	}
+	return descriptors, nil
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder builds tasks that record their payload when they run.
+type recorder struct {
+	m   sync.Mutex
+	ran []string
+}
+
+func (rec *recorder) factory(payload []byte) (func(int), error) {
+	return func(int) {
+		rec.m.Lock()
+		defer rec.m.Unlock()
+		rec.ran = append(rec.ran, string(payload))
+	}, nil
+}
+
+func TestPersistentQueueRecovers(t *testing.T) {
+	q := NewMemoryQueue()
+	rec := &recorder{}
+	r := NewWithOptions(time.Second, 1, WithPersistentQueue(q))
+	r.RegisterFactory("record", rec.factory)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := r.AddDescriptor(TaskDescriptor{ID: id, Name: "record", Payload: []byte(id)}); err != nil {
+			t.Fatalf("AddDescriptor(%q) = %v, want nil", id, err)
+		}
+	}
+	// the process stops before the run, leaving the descriptors behind
+	if saved, _ := q.Load(); len(saved) != 3 {
+		t.Fatalf("queue holds %d descriptors, want 3", len(saved))
+	}
+
+	r = NewWithOptions(time.Second, 1, WithPersistentQueue(q))
+	r.RegisterFactory("record", rec.factory)
+	if n, err := r.Recover(); n != 3 || err != nil {
+		t.Fatalf("Recover() = %d, %v, want 3, nil", n, err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(rec.ran) != 3 || rec.ran[0] != "a" || rec.ran[1] != "b" || rec.ran[2] != "c" {
+		t.Fatalf("recovered tasks ran with %q, want a, b and c in order", rec.ran)
+	}
+	if saved, _ := q.Load(); len(saved) != 0 {
+		t.Fatalf("queue holds %d descriptors after the run, want none", len(saved))
+	}
+}
+
+func TestPersistentQueueUnknownFactory(t *testing.T) {
+	q := NewMemoryQueue()
+	r := NewWithOptions(time.Second, 1, WithPersistentQueue(q))
+	err := r.AddDescriptor(TaskDescriptor{ID: "a", Name: "missing"})
+	if !errors.Is(err, ErrUnknownFactory) {
+		t.Fatalf("AddDescriptor() = %v, want %v", err, ErrUnknownFactory)
+	}
+	if saved, _ := q.Load(); len(saved) != 0 {
+		t.Fatalf("queue holds %d descriptors, want none", len(saved))
+	}
+}
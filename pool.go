@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+)
+
+// Pool spreads tasks across several runners, for instance to scale past
+// the worker pool of a single runner. Tasks submitted to the pool go to the
+// least loaded runner, runners equally loaded taking turns. Tasks may be
+// submitted before Wait, and while it runs.
+type Pool struct {
+	runners []*Runner
+
+	// m guards next, the runner to try first.
+	m    sync.Mutex
+	next int
+}
+
+// ErrPoolClosed is returned by Submit when no runner of the pool takes
+// tasks anymore, their runs having ended.
+var ErrPoolClosed = errors.New("no runner of the pool takes tasks")
+
+// NewPool returns a Pool spreading tasks across runners. NewPool panics if
+// runners is empty.
+func NewPool(runners ...*Runner) *Pool {
+	if len(runners) == 0 {
+		panic("runner: pool without runners")
+	}
+	return &Pool{runners: runners}
+}
+
+// Submit attaches task to the least loaded runner of the pool and returns
+// that runner. Runners whose run has ended, or whose workers have exited
+// for lack of work, are skipped since the task would never run there.
+// Submit returns ErrPoolClosed when no runner is left to take the task.
+func (p *Pool) Submit(task func(int)) (*Runner, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	skipped := make([]bool, len(p.runners))
+	for {
+		pos, least := -1, 0
+		for i := range p.runners {
+			j := (p.next + i) % len(p.runners)
+			if skipped[j] {
+				continue
+			}
+			if load := p.runners[j].load(); pos < 0 || load < least {
+				pos, least = j, load
+			}
+		}
+		if pos < 0 {
+			return nil, ErrPoolClosed
+		}
+		if p.runners[pos].offer(task) {
+			p.next = (pos + 1) % len(p.runners)
+			return p.runners[pos], nil
+		}
+		skipped[pos] = true
+	}
+}
+
+// Wait starts every runner of the pool and blocks until they have all
+// returned. It returns the errors of the runners joined together, or nil
+// if they all completed.
+func (p *Pool) Wait() error {
+	errs := make([]error, len(p.runners))
+	var wg sync.WaitGroup
+	for i, r := range p.runners {
+		wg.Add(1)
+		go func(i int, r *Runner) {
+			defer wg.Done()
+			errs[i] = r.Start()
+		}(i, r)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Results returns the results of the tasks that have run so far on each
+// runner of the pool, in the order of the runners. The indices of the
+// results are those of the tasks on their runner.
+func (p *Pool) Results() [][]TaskResult {
+	results := make([][]TaskResult, len(p.runners))
+	for i, r := range p.runners {
+		results[i] = r.Results()
+	}
+	return results
+}
+
+// load returns the number of tasks registered on r that have not finished.
+func (r *Runner) load() int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return len(r.tasks) - r.finished
+}
+
+// offer attaches task to r unless it would never run there, because the
+// run of r has ended or its workers have exited for lack of work, and
+// reports whether it did. A runner that has not started takes any task.
+func (r *Runner) offer(task func(int)) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+	// goroutines is zero until the workers are spawned, and live drops
+	// to zero as they exit
+	if r.returned != nil && !(r.dispatching && (r.live > 0 || r.goroutines == 0 || r.idleTimeout > 0)) {
+		return false
+	}
+	r.push(task)
+	return true
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolSpreadsTasks(t *testing.T) {
+	runners := []*Runner{New(time.Second, 2), New(time.Second, 2), New(time.Second, 2)}
+	p := NewPool(runners...)
+	var ran atomic.Int32
+	for i := 0; i < 30; i++ {
+		if _, err := p.Submit(func(int) { ran.Add(1) }); err != nil {
+			t.Fatalf("Submit() = %v, want nil", err)
+		}
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if got := ran.Load(); got != 30 {
+		t.Fatalf("%d tasks ran, want 30", got)
+	}
+	for i, results := range p.Results() {
+		if len(results) != 10 {
+			t.Fatalf("runner %d ran %d tasks, want 10", i, len(results))
+		}
+	}
+}
+
+func TestPoolSkipsEndedRunners(t *testing.T) {
+	ended, fresh := New(time.Second, 1), New(time.Second, 1)
+	ended.Add(func(int) {})
+	if err := ended.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	p := NewPool(ended, fresh)
+	for i := 0; i < 3; i++ {
+		r, err := p.Submit(func(int) {})
+		if err != nil || r != fresh {
+			t.Fatalf("Submit() = %p, %v, want the runner that has not run, nil", r, err)
+		}
+	}
+	if err := fresh.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if _, err := p.Submit(func(int) {}); err != ErrPoolClosed {
+		t.Fatalf("Submit() = %v once every run ended, want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestNewPoolWithoutRunners(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewPool() without runners did not panic")
+		}
+	}()
+	NewPool()
+}
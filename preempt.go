@@ -0,0 +1,50 @@
+package runner
+
+import "log/slog"
+
+// preempt cancels the running context-aware task of the lowest priority
+// when no worker is free for the Critical task just queued and every busy
+// worker runs a task below Critical, r.m must be held. Among tasks of the
+// same priority the latest registered is preempted.
+func (r *Runner) preempt() {
+	if r.synchronous || !r.dispatching || r.idle > 0 || r.live < r.numberOfWorker {
+		return
+	}
+	var victim *task
+	for _, t := range r.busy {
+		if t == nil {
+			continue
+		}
+		if t.priority >= Critical {
+			return
+		}
+		// cancel is only set while a context-aware task runs
+		if t.cancel == nil || t.canceled || t.preempted {
+			continue
+		}
+		if victim == nil || t.priority < victim.priority ||
+			(t.priority == victim.priority && t.index > victim.index) {
+			victim = t
+		}
+	}
+	if victim == nil {
+		return
+	}
+	victim.preempted = true
+	victim.cancel()
+	r.log("task preempted", slog.Int("task", victim.index))
+}
+
+// requeuePreempted queues t again if it was preempted, and reports whether
+// it did.
+func (r *Runner) requeuePreempted(t *task) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if !t.preempted {
+		return false
+	}
+	t.preempted = false
+	r.queue.push(t)
+	r.wakeFor(t)
+	return true
+}
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPreemption(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithPreemption())
+	var mu sync.Mutex
+	var events []string
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	started := make(chan struct{})
+	attempts := 0
+	r.AddContextTask(func(ctx context.Context, id int) {
+		attempts++
+		if attempts > 1 {
+			record("low rerun")
+			return
+		}
+		record("low started")
+		close(started)
+		<-ctx.Done()
+		record("low preempted")
+	})
+	go func() {
+		<-started
+		r.AddWithPriority(Critical, func(int) { record("critical") })
+	}()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	want := []string{"low started", "low preempted", "critical", "low rerun"}
+	if len(events) != len(want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("events = %q, want %q", events, want)
+		}
+	}
+}
+
+func TestNoPreemptionWhileCriticalRuns(t *testing.T) {
+	r := NewWithOptions(time.Second, 2, WithPreemption())
+	release := make(chan struct{})
+	critical := make(chan struct{})
+	r.AddWithPriority(Critical, func(int) {
+		close(critical)
+		<-release
+	})
+	low := make(chan context.Context, 1)
+	r.AddContextTask(func(ctx context.Context, id int) {
+		low <- ctx
+		<-release
+	})
+	errc := make(chan error)
+	go func() { errc <- r.Start() }()
+	<-critical
+	ctx := <-low
+	// one worker runs Critical work, so the new task waits for a worker
+	r.AddWithPriority(Critical, func(int) {})
+	if ctx.Err() != nil {
+		t.Fatal("a task was preempted while a worker ran a Critical task")
+	}
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+}
+
+func TestNoPreemptionByDefault(t *testing.T) {
+	r := New(time.Second, 1)
+	started := make(chan struct{})
+	var preempted bool
+	r.AddContextTask(func(ctx context.Context, id int) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			preempted = true
+		case <-time.After(30 * time.Millisecond):
+		}
+	})
+	go func() {
+		<-started
+		r.AddWithPriority(Critical, func(int) {})
+	}()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if preempted {
+		t.Fatal("a running task was preempted without WithPreemption")
+	}
+}
@@ -0,0 +1,116 @@
+package runner
+
+// Priority orders the dispatch of pending tasks: of the tasks a worker may
+// run, it is handed the one with the highest priority, first come first
+// served among equals.
+type Priority int
+
+// The task priorities, from lowest to highest. Tasks added without a
+// priority are Normal.
+const (
+	Low Priority = iota - 1
+	Normal
+	High
+	Critical
+)
+
+// numPriorities is the number of priority levels.
+const numPriorities = int(Critical-Low) + 1
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case Low:
+		return "low"
+	case Normal:
+		return "normal"
+	case High:
+		return "high"
+	case Critical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// queue holds the pending tasks, first in first out within a priority.
+type queue struct {
+	levels [numPriorities][]*task
+	n      int
+}
+
+// level returns the slot of p in levels.
+func level(p Priority) int {
+	return int(p - Low)
+}
+
+// reserve makes room for n more Normal tasks.
+func (q *queue) reserve(n int) {
+	q.levels[level(Normal)] = grow(q.levels[level(Normal)], n)
+}
+
+// push appends t to the queue of its priority.
+func (q *queue) push(t *task) {
+	l := level(t.priority)
+	q.levels[l] = append(q.levels[l], t)
+	q.n++
+}
+
+// take removes and returns the first task of the highest priority that
+// worker id may run, or nil if there is none.
+func (q *queue) take(id int) *task {
+	for l := numPriorities - 1; l >= 0; l-- {
+		tasks := q.levels[l]
+		for i, t := range tasks {
+			if x := t.ext(); x.affine && x.worker != id {
+				continue
+			}
+			if i == 0 {
+				tasks[0] = nil
+				q.levels[l] = tasks[1:]
+			} else {
+				q.levels[l] = append(tasks[:i], tasks[i+1:]...)
+			}
+			q.n--
+			return t
+		}
+	}
+	return nil
+}
+
+// remove takes t out of the queue and reports whether it was there.
+func (q *queue) remove(t *task) bool {
+	l := level(t.priority)
+	for i, p := range q.levels[l] {
+		if p == t {
+			q.levels[l] = append(q.levels[l][:i], q.levels[l][i+1:]...)
+			q.n--
+			return true
+		}
+	}
+	return false
+}
+
+// pending returns the tasks in the queue, leaving them there.
+func (q *queue) pending() []*task {
+	tasks := make([]*task, 0, q.n)
+	for l := range q.levels {
+		tasks = append(tasks, q.levels[l]...)
+	}
+	return tasks
+}
+
+// clear empties the queue and returns the tasks it held.
+func (q *queue) clear() []*task {
+	var tasks []*task
+	for l := range q.levels {
+		tasks = append(tasks, q.levels[l]...)
+		q.levels[l] = nil
+	}
+	q.n = 0
+	return tasks
+}
+
+// len returns the number of pending tasks.
+func (q *queue) len() int {
+	return q.n
+}
@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriorityDispatchOrder(t *testing.T) {
+	r := New(time.Second, 1)
+	var order []string
+	add := func(p Priority, name string) {
+		r.AddWithPriority(p, func(int) { order = append(order, name) })
+	}
+	add(Low, "low")
+	add(Normal, "normal-1")
+	add(Critical, "critical")
+	add(High, "high")
+	r.Add(func(int) { order = append(order, "normal-2") })
+	add(Critical, "critical-2")
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	want := []string{"critical", "critical-2", "high", "normal-1", "normal-2", "low"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestPriorityString(t *testing.T) {
+	for p, want := range map[Priority]string{Low: "low", Normal: "normal", High: "high", Critical: "critical", 7: "unknown"} {
+		if got := p.String(); got != want {
+			t.Fatalf("%d.String() = %q, want %q", int(p), got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package runner
+
+import "time"
+
+// RunReport summarizes a run.
+type RunReport struct {
+	// DispatchStart is when Start began dispatching tasks.
+	DispatchStart time.Time
+
+	// FirstTaskStart is when the first task was handed to a worker.
+	FirstTaskStart time.Time
+
+	// LastTaskEnd is when the last task to finish did.
+	LastTaskEnd time.Time
+
+	// ShutdownComplete is when the last worker exited. It is zero as long
+	// as tasks are still running, which can outlast Start on a timeout.
+	ShutdownComplete time.Time
+
+	// Succeeded and Failed count the tasks that ran without and with an
+	// error.
+	Succeeded, Failed int
+}
+
+// Elapsed returns the time from the start of the dispatch to the exit of
+// the last worker.
+func (rep RunReport) Elapsed() time.Duration {
+	return rep.ShutdownComplete.Sub(rep.DispatchStart)
+}
+
+// Scheduling returns the time it took for the first task to start.
+func (rep RunReport) Scheduling() time.Duration {
+	return rep.FirstTaskStart.Sub(rep.DispatchStart)
+}
+
+// Execution returns the time from the start of the first task to the end
+// of the last one.
+func (rep RunReport) Execution() time.Duration {
+	return rep.LastTaskEnd.Sub(rep.FirstTaskStart)
+}
+
+// Draining returns the time from the end of the last task to the exit of
+// the last worker.
+func (rep RunReport) Draining() time.Duration {
+	return rep.ShutdownComplete.Sub(rep.LastTaskEnd)
+}
+
+// Report returns the report of the current or last run.
+func (r *Runner) Report() RunReport {
+	r.m.Lock()
+	defer r.m.Unlock()
+	rep := r.timeline
+	rep.Succeeded, rep.Failed = r.succeeded, r.failed
+	return rep
+}
+
+// StartedAt returns the time Start began dispatching the current or last
+// run, or the zero time before the first run.
+func (r *Runner) StartedAt() time.Time {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.timeline.DispatchStart
+}
+
+// Deadline returns the time the current or last run times out at, which is
+// StartedAt plus the timeout or the deadline set with WithDeadline if that
+// is earlier. It returns the zero time before the first run.
+func (r *Runner) Deadline() time.Time {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.runDeadline
+}
+
+// EstimatedCompletion returns a rough estimate of the time the tasks left
+// in the current run will be done by, from the average duration of the
+// tasks that have run so far and the number of workers. It reports false
+// when no task has run yet and there is nothing to go by.
+func (r *Runner) EstimatedCompletion() (time.Time, bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	var spent time.Duration
+	var ran int
+	for _, t := range r.tasks {
+		if t.ran && !t.result.Skipped && !t.result.Cached {
+			spent += t.result.Duration
+			ran++
+		}
+	}
+	if ran == 0 || r.numberOfWorker <= 0 {
+		return time.Time{}, false
+	}
+	avg := spent / time.Duration(ran)
+	left := len(r.tasks) - r.finished
+	rounds := (left + r.numberOfWorker - 1) / r.numberOfWorker
+	return time.Now().Add(avg * time.Duration(rounds)), true
+}
+
+// DispatchOrder returns the indices of the tasks of the current or last
+// run in the order they were handed to the workers so far, which helps
+// reproducing failures that depend on the order. A sliced task appears once
+// per slice.
+func (r *Runner) DispatchOrder() []int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return append([]int(nil), r.dispatched...)
+}
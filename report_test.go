@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReportPhases(t *testing.T) {
+	r := New(time.Second, 2)
+	r.Add(func(int) { time.Sleep(20 * time.Millisecond) })
+	r.Add(func(int) { time.Sleep(10 * time.Millisecond) })
+	before := time.Now()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	rep := r.Report()
+	phases := []time.Time{before, rep.DispatchStart, rep.FirstTaskStart, rep.LastTaskEnd, rep.ShutdownComplete, time.Now()}
+	for i := 1; i < len(phases); i++ {
+		if phases[i].Before(phases[i-1]) {
+			t.Fatalf("phase %d at %v precedes phase %d at %v", i, phases[i], i-1, phases[i-1])
+		}
+	}
+	if d := rep.Execution(); d < 20*time.Millisecond {
+		t.Fatalf("Execution() = %v, want at least the longest task", d)
+	}
+	if rep.Elapsed() != rep.Scheduling()+rep.Execution()+rep.Draining() {
+		t.Fatal("the phases do not add up to Elapsed")
+	}
+	if rep.Succeeded != 2 || rep.Failed != 0 {
+		t.Fatalf("report counts %d succeeded and %d failed, want 2 and 0", rep.Succeeded, rep.Failed)
+	}
+}
+
+func TestStartedAtAndDeadline(t *testing.T) {
+	r := New(time.Second, 1)
+	if !r.StartedAt().IsZero() || !r.Deadline().IsZero() {
+		t.Fatalf("StartedAt() = %v and Deadline() = %v before the run, want zero times", r.StartedAt(), r.Deadline())
+	}
+	r.Add(func(int) {})
+	before := time.Now()
+	r.Start()
+	started := r.StartedAt()
+	if started.Before(before) || started.After(time.Now()) {
+		t.Fatalf("StartedAt() = %v, want within the run", started)
+	}
+	if got, want := r.Deadline(), started.Add(time.Second); !got.Equal(want) {
+		t.Fatalf("Deadline() = %v, want %v", got, want)
+	}
+
+	early := time.Now().Add(100 * time.Millisecond)
+	r = NewWithOptions(time.Hour, 1, WithDeadline(early))
+	r.Add(func(int) {})
+	r.Start()
+	if got := r.Deadline(); !got.Equal(early) {
+		t.Fatalf("Deadline() = %v, want the absolute deadline %v", got, early)
+	}
+}
+
+func TestCompletionCallbacks(t *testing.T) {
+	tests := []struct {
+		name string
+		add  func(r *Runner, n *fakeNotifier)
+		want error
+	}{
+		{"complete", func(r *Runner, n *fakeNotifier) { r.Add(func(int) {}) }, nil},
+		{"timeout", func(r *Runner, n *fakeNotifier) { r.Add(blocker(r)) }, ErrTimeout},
+		{"interrupt", func(r *Runner, n *fakeNotifier) {
+			r.Add(blocker(r))
+			go n.send(t, os.Interrupt)
+		}, ErrInterrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &fakeNotifier{}
+			timeout := time.Second
+			if tt.want == ErrTimeout {
+				timeout = 20 * time.Millisecond
+			}
+			r := NewWithOptions(timeout, 1, WithNotifier(n))
+			var completed, terminated int
+			var terminatedWith error
+			r.OnComplete(func(RunReport) { completed++ })
+			r.OnTerminate(func(_ RunReport, err error) {
+				terminated++
+				terminatedWith = err
+			})
+			tt.add(r, n)
+			if err := r.Start(); err != tt.want {
+				t.Fatalf("Start() = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil {
+				if completed != 1 || terminated != 0 {
+					t.Fatalf("OnComplete called %d and OnTerminate %d times, want 1 and 0", completed, terminated)
+				}
+				return
+			}
+			if completed != 0 || terminated != 1 || terminatedWith != tt.want {
+				t.Fatalf("OnComplete called %d and OnTerminate %d times with %v, want 0 and 1 with %v",
+					completed, terminated, terminatedWith, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimatedCompletion(t *testing.T) {
+	r := New(5*time.Second, 2)
+	if _, ok := r.EstimatedCompletion(); ok {
+		t.Fatal("EstimatedCompletion() reported an estimate before any task ran")
+	}
+	var estimate time.Time
+	var ok bool
+	for i := 0; i < 10; i++ {
+		i := i
+		r.Add(func(int) {
+			if i == 6 {
+				estimate, ok = r.EstimatedCompletion()
+			}
+			time.Sleep(20 * time.Millisecond)
+		})
+	}
+	r.Start()
+	if !ok {
+		t.Fatal("EstimatedCompletion() reported no estimate mid-run")
+	}
+	// with 6 tasks finished, 4 are left for 2 workers: 2 rounds of 20ms
+	end := r.Report().ShutdownComplete
+	if diff := estimate.Sub(end); diff < -30*time.Millisecond || diff > 30*time.Millisecond {
+		t.Fatalf("EstimatedCompletion() = %v, %v off the actual end", estimate, diff)
+	}
+}
+
+func TestDispatchOrder(t *testing.T) {
+	r := New(time.Second, 1)
+	var started []int
+	for i := 0; i < 6; i++ {
+		i := i
+		p := Normal
+		if i%2 == 1 {
+			p = High
+		}
+		r.AddWithPriority(p, func(int) { started = append(started, i) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	order := r.DispatchOrder()
+	if len(order) != len(started) {
+		t.Fatalf("DispatchOrder() = %v, tasks started in order %v", order, started)
+	}
+	for i := range order {
+		if order[i] != started[i] {
+			t.Fatalf("DispatchOrder() = %v, tasks started in order %v", order, started)
+		}
+	}
+}
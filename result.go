@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"sort"
+	"time"
+)
+
+// TaskResult is the outcome of a task that has run.
+type TaskResult struct {
+	// Index is the registration index of the task.
+	Index int
+
+	// Worker is the id of the worker that ran the task, or that ran its
+	// last slice for a task added with AddSliced. It is 0 under
+	// WithSynchronous.
+	Worker int
+
+	// Value is the value produced by the task, if any.
+	Value any
+
+	// Err is the error returned by the task, if any.
+	Err error
+
+	// Duration is the time the task took to run, retries included.
+	Duration time.Duration
+
+	// Attempts is the number of times the task was run.
+	Attempts int
+
+	// Skipped reports whether the task was skipped rather than started
+	// because its estimated duration exceeded the time left in the run or
+	// the task filter dropped it. Err is then ErrTaskSkipped.
+	Skipped bool
+
+	// AllocBytes and Allocs are the bytes and the number of objects
+	// allocated while the task ran, recorded under WithAllocProfiling.
+	AllocBytes, Allocs uint64
+
+	// Cached reports whether the result was served from the result cache
+	// instead of running the task.
+	Cached bool
+}
+
+// Cache stores the results of keyed tasks across runs. Implementations
+// must be safe for concurrent use by the workers.
+type Cache interface {
+	// Get returns the result stored under key, if any.
+	Get(key string) (TaskResult, bool)
+
+	// Set stores result under key.
+	Set(key string, result TaskResult)
+}
+
+// AddKeyed attaches a task identified by key that produces a value. When
+// the runner has a result cache holding key, the cached result is used and
+// the task does not run. Successful results are stored in the cache.
+func (r *Runner) AddKeyed(key string, fn func(int) (any, error)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	t := r.push(nil)
+	t.efn = fn
+	x := t.more()
+	x.keyed = true
+	x.key = key
+}
+
+// Results returns the results of the tasks that have run so far, in
+// registration order. Under WithResultRingBuffer it returns the results
+// retained by the ring instead, in the order the tasks finished.
+func (r *Runner) Results() []TaskResult {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.ringSize > 0 {
+		return r.ringResults()
+	}
+	var results []TaskResult
+	for _, t := range r.tasks {
+		if t.ran {
+			results = append(results, t.result)
+		}
+	}
+	return results
+}
+
+// ResultsByCompletion returns the results of the tasks that have run so
+// far in the order they finished, which shows how the run unfolded.
+func (r *Runner) ResultsByCompletion() []TaskResult {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.ringSize > 0 {
+		// the ring holds them in that order already
+		return r.ringResults()
+	}
+	var ran []*task
+	for _, t := range r.tasks {
+		if t.ran {
+			ran = append(ran, t)
+		}
+	}
+	sort.SliceStable(ran, func(i, j int) bool {
+		return ran[i].ended.Before(ran[j].ended)
+	})
+	results := make([]TaskResult, len(ran))
+	for i, t := range ran {
+		results[i] = t.result
+	}
+	return results
+}
+
+// keep stores res in the result ring, overwriting the oldest result once
+// the ring is full, r.m must be held.
+func (r *Runner) keep(res TaskResult) {
+	if len(r.ring) < r.ringSize {
+		r.ring = append(r.ring, res)
+		return
+	}
+	r.ring[r.next] = res
+	r.next = (r.next + 1) % r.ringSize
+	r.dropped++
+}
+
+// ringResults returns the results held by the result ring, oldest first,
+// r.m must be held.
+func (r *Runner) ringResults() []TaskResult {
+	results := make([]TaskResult, 0, len(r.ring))
+	results = append(results, r.ring[r.next:]...)
+	return append(results, r.ring[:r.next]...)
+}
+
+// DroppedResults returns the number of results the result ring set with
+// WithResultRingBuffer has dropped to make room for newer ones.
+func (r *Runner) DroppedResults() int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.dropped
+}
@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// mapCache is a Cache backed by a map.
+type mapCache struct {
+	mu      sync.Mutex
+	results map[string]TaskResult
+}
+
+func (c *mapCache) Get(key string) (TaskResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res, ok := c.results[key]
+	return res, ok
+}
+
+func (c *mapCache) Set(key string, res TaskResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.results == nil {
+		c.results = make(map[string]TaskResult)
+	}
+	c.results[key] = res
+}
+
+func TestResultCacheShortCircuits(t *testing.T) {
+	cache := &mapCache{}
+	r := NewWithOptions(time.Second, 1, WithResultCache(cache))
+	calls := 0
+	fn := func(int) (any, error) {
+		calls++
+		return "value", nil
+	}
+	r.AddKeyed("k", fn)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if res := r.Results()[0]; res.Cached || res.Value != "value" {
+		t.Fatalf("first run result = %+v, want an uncached value", res)
+	}
+	// a second runner sharing the cache
+	r = NewWithOptions(time.Second, 1, WithResultCache(cache))
+	r.AddKeyed("k", fn)
+	r.AddKeyed("other", fn)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if calls != 2 {
+		t.Fatalf("task ran %d times, want 2", calls)
+	}
+	results := r.Results()
+	if !results[0].Cached || results[0].Value != "value" {
+		t.Fatalf("second run result = %+v, want the cached value", results[0])
+	}
+	if results[1].Cached {
+		t.Fatal("a key missing from the cache was served from it")
+	}
+}
+
+func TestResultRingBuffer(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithResultRingBuffer(3))
+	for i := 0; i < 10; i++ {
+		r.Add(func(int) {})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	res := r.Results()
+	if len(res) != 3 {
+		t.Fatalf("Results() holds %d results, want 3", len(res))
+	}
+	for i, want := range []int{7, 8, 9} {
+		if res[i].Index != want {
+			t.Fatalf("Results()[%d].Index = %d, want %d", i, res[i].Index, want)
+		}
+	}
+	if got := r.DroppedResults(); got != 7 {
+		t.Fatalf("DroppedResults() = %d, want 7", got)
+	}
+}
+
+func TestResultsByCompletion(t *testing.T) {
+	r := New(time.Second, 3)
+	for _, d := range []time.Duration{30, 10, 20} {
+		d := d * time.Millisecond
+		r.Add(func(int) { time.Sleep(d) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	byIndex, byCompletion := r.Results(), r.ResultsByCompletion()
+	for i, want := range []int{0, 1, 2} {
+		if byIndex[i].Index != want {
+			t.Fatalf("Results()[%d].Index = %d, want %d", i, byIndex[i].Index, want)
+		}
+	}
+	for i, want := range []int{1, 2, 0} {
+		if byCompletion[i].Index != want {
+			t.Fatalf("ResultsByCompletion()[%d].Index = %d, want %d", i, byCompletion[i].Index, want)
+		}
+	}
+}
+
+var allocSink [][]byte
+
+func TestAllocProfiling(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithAllocProfiling())
+	r.Add(func(int) {
+		for i := 0; i < 10; i++ {
+			allocSink = append(allocSink, make([]byte, 1<<20))
+		}
+	})
+	r.Add(func(int) {})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	allocSink = nil
+	if res := r.Results()[0]; res.AllocBytes < 10<<20 || res.Allocs == 0 {
+		t.Fatalf("recorded %d bytes in %d allocations, want at least 10MiB", res.AllocBytes, res.Allocs)
+	}
+
+	r = New(time.Second, 1)
+	r.Add(func(int) { allocSink = append(allocSink, make([]byte, 1<<20)) })
+	r.Start()
+	allocSink = nil
+	if res := r.Results()[0]; res.AllocBytes != 0 {
+		t.Fatalf("recorded %d bytes without WithAllocProfiling, want 0", res.AllocBytes)
+	}
+}
+
+func TestResultsRecordWorker(t *testing.T) {
+	r := New(time.Second, 4)
+	for i := 0; i < 40; i++ {
+		r.Add(func(int) { time.Sleep(2 * time.Millisecond) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	perWorker := make(map[int]int)
+	for _, res := range r.Results() {
+		if res.Worker < 0 || res.Worker >= 4 {
+			t.Fatalf("task %d ran on worker %d, want one of the 4 workers", res.Index, res.Worker)
+		}
+		perWorker[res.Worker]++
+	}
+	for id := 0; id < 4; id++ {
+		if n := perWorker[id]; n < 5 || n > 15 {
+			t.Fatalf("tasks per worker = %v, want about 10 each", perWorker)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPermanent marks the errors that are not worth a retry. A task whose
+// error wraps it fails right away, whatever the retry policy.
+var ErrPermanent = errors.New("permanent error")
+
+// RetryPolicy tells how failed tasks are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a task is run at most, first
+	// attempt included. Zero or one disables retries.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It doubles with each
+	// further retry.
+	Backoff time.Duration
+}
+
+// delay returns the time to wait after the given failed attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.Backoff << (attempt - 1)
+}
+
+// AddRetryable attaches tasks that are retried according to the retry
+// policy when they return an error. Each task is passed its own
+// registration index as id, which stays the same across retries, and the
+// number of the attempt, starting at 1.
+func (r *Runner) AddRetryable(tasks ...func(id, attempt int) error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		t := r.push(nil)
+		t.efn = func(int) (any, error) {
+			return nil, fn(t.index, t.attempt)
+		}
+	}
+}
+
+// AddWithRetry attaches a fallible task retried according to policy rather
+// than the policy of the runner, see WithRetryPolicy. The retry classifier,
+// the retry budget and ErrPermanent still apply. The task is passed the id
+// of the worker running it, as with AddFallible.
+func (r *Runner) AddWithRetry(policy RetryPolicy, fn func(int) error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	t := r.push(nil)
+	t.more().retry = &policy
+	t.efn = func(id int) (any, error) {
+		return nil, fn(id)
+	}
+}
+
+// retryable reports whether a task that failed with err may be retried.
+func (r *Runner) retryable(err error) bool {
+	if errors.Is(err, ErrPermanent) {
+		return false
+	}
+	return r.retryableErr == nil || r.retryableErr(err)
+}
+
+// takeRetry reports whether the retry budget allows one more retry and
+// counts it if so.
+func (r *Runner) takeRetry() bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.retryBudget > 0 && r.retries >= r.retryBudget {
+		return false
+	}
+	r.retries++
+	return true
+}
+
+// sleep waits for d and reports whether the run is still in progress
+// afterwards, returning early when done, the done channel of the run, is
+// closed.
+func (r *Runner) sleep(done <-chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryKeepsTaskID(t *testing.T) {
+	r := NewWithOptions(time.Second, 2, WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+	var ids, attempts []int
+	r.Add(func(int) {})
+	r.AddRetryable(func(id, attempt int) error {
+		ids = append(ids, id)
+		attempts = append(attempts, attempt)
+		if attempt < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("task ran %d times, want 3", len(attempts))
+	}
+	for i := range attempts {
+		if ids[i] != 1 || attempts[i] != i+1 {
+			t.Fatalf("run %d got id %d and attempt %d, want id 1 and attempt %d", i, ids[i], attempts[i], i+1)
+		}
+	}
+}
+
+func TestRetryOnPanic(t *testing.T) {
+	r := NewWithOptions(time.Second, 1,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2}),
+		WithRetryOnPanic(),
+	)
+	var once atomic.Bool
+	r.Add(func(int) {
+		if !once.Swap(true) {
+			var m map[string]int
+			m["boom"]++
+		}
+	})
+	r.Add(func(int) { panic("always") })
+	r.Start()
+	res := r.Results()
+	if res[0].Err != nil || res[0].Attempts != 2 {
+		t.Fatalf("Results()[0] = %+v, want success on the second attempt", res[0])
+	}
+	if !errors.Is(res[1].Err, ErrTaskPanicked) || res[1].Attempts != 2 {
+		t.Fatalf("Results()[1] = %+v, want %v after 2 attempts", res[1], ErrTaskPanicked)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	r := NewWithOptions(time.Second, 2,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5}),
+		WithRetryBudget(3),
+	)
+	var runs atomic.Int32
+	for i := 0; i < 4; i++ {
+		r.AddFallible(func(int) error {
+			runs.Add(1)
+			return errors.New("flaky")
+		})
+	}
+	r.Start()
+	if got := runs.Load(); got != 4+3 {
+		t.Fatalf("tasks ran %d times, want 4 first attempts and 3 retries", got)
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	r := NewWithOptions(time.Second, 1,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}))
+	type retry struct {
+		attempt int
+		delay   time.Duration
+	}
+	var retries []retry
+	r.OnRetry(func(index, attempt int, err error, nextDelay time.Duration) {
+		if index != 0 || err == nil {
+			t.Errorf("OnRetry(%d, %d, %v, %v), want task 0 with its error", index, attempt, err, nextDelay)
+		}
+		retries = append(retries, retry{attempt, nextDelay})
+	})
+	fails := 0
+	r.AddFallible(func(int) error {
+		if fails < 2 {
+			fails++
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	want := []retry{{1, time.Millisecond}, {2, 2 * time.Millisecond}}
+	if len(retries) != len(want) || retries[0] != want[0] || retries[1] != want[1] {
+		t.Fatalf("OnRetry saw %v, want %v", retries, want)
+	}
+}
+
+func TestRetryClassification(t *testing.T) {
+	errTransient, errFatal := errors.New("transient"), errors.New("fatal")
+	r := NewWithOptions(time.Second, 1,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3}),
+		WithRetryClassifier(func(err error) bool { return err != errFatal }),
+	)
+	r.AddFallible(func(int) error { return fmt.Errorf("bad input: %w", ErrPermanent) })
+	r.AddFallible(func(int) error { return errFatal })
+	r.AddFallible(func(int) error { return errTransient })
+	r.Start()
+	for i, want := range []int{1, 1, 3} {
+		if got := r.Results()[i].Attempts; got != want {
+			t.Fatalf("task %d made %d attempts, want %d", i, got, want)
+		}
+	}
+}
+
+func TestAddWithRetryOverridesPolicy(t *testing.T) {
+	r := NewWithOptions(time.Second, 2, WithRetryPolicy(RetryPolicy{MaxAttempts: 2}))
+	var runs [3]atomic.Int32
+	fail := func(i int) func(int) error {
+		return func(int) error {
+			runs[i].Add(1)
+			return errors.New("flaky")
+		}
+	}
+	r.AddWithRetry(RetryPolicy{MaxAttempts: 4}, fail(0))
+	r.AddWithRetry(RetryPolicy{MaxAttempts: 1}, fail(1))
+	r.AddFallible(fail(2))
+	r.Start()
+	for i, want := range []int32{4, 1, 2} {
+		if got := runs[i].Load(); got != want {
+			t.Fatalf("task %d ran %d times, want %d", i, got, want)
+		}
+	}
+}
@@ -2,10 +2,16 @@
 package runner
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
-	"os/signal"
+	"runtime"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -16,144 +22,1619 @@ type Runner struct {
 	// operating system.
 	interrupt chan os.Signal
 
-	// complete channel reports that processing is done.
-	complete chan error
+	// notifier relays the signals to interrupt and to the handlers
+	// registered with OnSignal.
+	notifier Notifier
+
+	// ignoreSignals keeps the runner from listening to any signal, see
+	// WithoutSignalHandling.
+	ignoreSignals bool
+
+	// received is the signal that interrupted the run, guarded by m.
+	received os.Signal
+
+	// complete channel reports the tasks that have run and the workers
+	// that have exited to the master goroutine.
+	complete chan completion
 
 	// complete channel reports that processing is done.
 	completeMain chan error
 
+	// mainBuffer is the buffer of completeMain, see WithChannelBuffers.
+	mainBuffer int
+
+	// done is closed when the run ends, no task is dispatched afterwards.
+	done chan struct{}
+
+	// endOnce guards the end of the run.
+	endOnce sync.Once
+
+	// ctx is the context of the run, the context-aware tasks derive
+	// theirs from it. cancelRun cancels it when the run ends.
+	ctx       context.Context
+	cancelRun context.CancelFunc
+
+	// returned is closed when Start returns, exited once the master
+	// goroutine and every worker of the run have returned.
+	returned, exited chan struct{}
+
+	// result is the error Start returned for the last run, guarded by m.
+	result error
+
+	// usage is the resource usage of the last run, or usageErr the error
+	// sampling it, guarded by m.
+	usage    Rusage
+	usageErr error
+
+	// endedWith is the error the run ended with, set when done is
+	// closed. flushed holds the channels of the Flush calls waiting for
+	// the queue to empty. Both are guarded by m.
+	endedWith error
+	flushed   []chan struct{}
+
 	// timeout reports that time has run out.
 	timeout <-chan time.Time
 
-	// tasks holds a set of functions that are executed
-	// synchronously in index order.
-	tasks []func(int)
+	// timeoutDuration is the time the run has to finish, counted from
+	// the call to Start.
+	timeoutDuration time.Duration
+
+	// deadline is the absolute time by which the run has to finish,
+	// zero means none.
+	deadline time.Time
+
+	// runDeadline is the time the current run ends at, the earlier of the
+	// timeout and the deadline. It is set by Start.
+	runDeadline time.Time
+
+	// tasks holds every registered task in index order.
+	tasks []*task
+
+	// queue holds the tasks waiting for a worker.
+	queue queue
+
+	// held holds the tasks of the stages that have not started yet, by
+	// stage, and heldCount counts them, see AddStage.
+	held      map[int][]*task
+	heldCount int
+
+	// sequences holds the sequential groups by name and sequenced counts
+	// the tasks waiting in them, see AddSequentialGroup.
+	sequences map[string]*sequence
+	sequenced int
+
+	// chunk is the unused tail of the last block of tasks allocated.
+	chunk []task
 
 	//mutex
 	m sync.Mutex
 
-	// terminate controlles the termination of workers
-	terminate bool
+	// cond wakes up the workers waiting for a task, bound to m.
+	cond *sync.Cond
+
+	// spawned counts the goroutines started by the runner that have not
+	// returned yet, see GoroutineCount.
+	spawned atomic.Int32
 
 	// number of worker to spin up
 	numberOfWorker int
+
+	// alive marks the worker ids that have a running worker, live counts
+	// them and idle counts those waiting for a task. goroutines counts the
+	// workers whose exit the master goroutine has yet to receive.
+	alive      []bool
+	live, idle int
+	goroutines int
+
+	// burstWorker adds a standby worker that runs while more than
+	// burstDepth tasks are queued, see WithBurstWorker.
+	burstWorker bool
+	burstDepth  int
+
+	// busy holds the task each worker id is running, nil while it has
+	// none, guarded by m.
+	busy []*task
+
+	// dispatching is set while the run is in progress.
+	dispatching bool
+
+	// synchronous runs the tasks on the goroutine calling Start, see
+	// WithSynchronous.
+	synchronous bool
+
+	// rampUp is the delay between the start of two workers at the
+	// beginning of the run, see WithRampUp.
+	rampUp time.Duration
+
+	// idleTimeout is how long a worker waits for a task before exiting,
+	// zero keeps workers until the run ends.
+	idleTimeout time.Duration
+
+	// workBudget caps the cumulative duration of completed tasks,
+	// zero means no budget.
+	workBudget time.Duration
+
+	// workSpent is the cumulative duration of completed tasks.
+	workSpent time.Duration
+
+	// reportedCPU is the CPU time reported by the tasks, see
+	// AddWithCPUReport.
+	reportedCPU time.Duration
+
+	// logger receives the lifecycle events, nil keeps the runner silent.
+	logger *slog.Logger
+
+	// name tags the lifecycle events, see WithName.
+	name string
+
+	// quorum is the number of successful tasks after which the run ends,
+	// zero waits for every task.
+	quorum int
+
+	// defaultTask runs in the slots left empty, see WithDefaultTask.
+	defaultTask func(int)
+
+	// softTaskTimeout keeps per-task timeouts from counting as failures,
+	// see WithTaskTimeoutSoft.
+	softTaskTimeout bool
+
+	// allocProfiling records the allocations of each task, see
+	// WithAllocProfiling.
+	allocProfiling bool
+
+	// filter may replace or drop each task before it runs, see
+	// WithTaskFilter.
+	filter func(index int, task func(int)) func(int)
+
+	// checkpointStore receives the indices of the completed tasks every
+	// checkpointInterval, see WithCheckpoint. skipStore holds those of an
+	// earlier run, see SkipCompleted.
+	checkpointStore    CheckpointStore
+	checkpointInterval time.Duration
+	skipStore          CheckpointStore
+
+	// saving serializes the checkpoint saves.
+	saving sync.Mutex
+
+	// persist stores the descriptors of the tasks added with
+	// AddDescriptor until they have run, factories builds their functions.
+	persist   PersistentQueue
+	factories map[string]TaskFactory
+
+	// failureLimit is the number of tasks failing at the same time that
+	// ends the run, zero means no limit. failures holds the spans of the
+	// last failed runs, guarded by m.
+	failureLimit int
+	failures     []span
+
+	// timeSlice is the quantum of the sliced tasks, see WithTimeSlice.
+	timeSlice time.Duration
+
+	// ringSize caps the number of results retained, zero keeps them
+	// all. ring holds the last results, next is where the next one goes
+	// and dropped counts those overwritten, guarded by m.
+	ringSize int
+	ring     []TaskResult
+	next     int
+	dropped  int
+
+	// groupScorer scores the results of each group, see
+	// WithGroupScorer.
+	groupScorer func(name string, results []TaskResult) float64
+
+	// first receives the result of the first task to finish, see First,
+	// guarded by m.
+	first chan TaskResult
+
+	// errStream streams the failures, see ErrorStream, and
+	// droppedErrors counts those it had no room for, guarded by m.
+	errStream     chan TaskError
+	droppedErrors int
+
+	// preemption lets a Critical task take the worker of a running
+	// context-aware task of lower priority, see WithPreemption.
+	preemption bool
+
+	// completed decides whether the run is done after each task, see
+	// WithCompletionPredicate.
+	completed func(RunnerMetrics) bool
+
+	// panicHandler is called with the panics recovered from tasks, nil
+	// logs them. noRecover lets a panic crash the process when there is
+	// no handler, see WithoutPanicRecovery.
+	panicHandler func(taskIndex int, recovered any, stack []byte)
+	noRecover    bool
+
+	// retry is the policy failed tasks are retried with, retryOnPanic
+	// applies it to panicking tasks as well.
+	retry        RetryPolicy
+	retryOnPanic bool
+
+	// retryableErr tells the errors worth a retry, see
+	// WithRetryClassifier.
+	retryableErr func(error) bool
+
+	// onRetry is called before each retry, see OnRetry.
+	onRetry func(index, attempt int, err error, nextDelay time.Duration)
+
+	// retryBudget caps the number of retries of the run, zero means no
+	// cap. retries counts the retries made so far, guarded by m.
+	retryBudget, retries int
+
+	// aggregators are fed every task result from the master goroutine.
+	aggregators []func(TaskResult)
+
+	// cache serves and stores the results of keyed tasks, nil disables
+	// caching.
+	cache Cache
+
+	// timeoutDrain makes Start wait for the running tasks when the
+	// timeout or the deadline passes.
+	timeoutDrain bool
+
+	// maxHeap is the heap size above which no task is dispatched, zero
+	// means no limit.
+	maxHeap uint64
+
+	// source is pulled for tasks once the queue is empty, sourceDrained is
+	// set once it is exhausted and pulling while a worker calls Next.
+	source        TaskSource
+	sourceDrained bool
+	pulling       bool
+
+	// yield makes workers yield the processor between tasks.
+	yield bool
+
+	// startJitter is the upper bound of the random wait before each task.
+	startJitter time.Duration
+
+	// signalHandlers holds the handlers of the non-terminating signals
+	// registered with OnSignal.
+	signalHandlers map[os.Signal][]func()
+
+	// slowTask is called for the tasks that ran longer than slowAfter,
+	// see OnSlowTask.
+	slowAfter time.Duration
+	slowTask  func(index int, d time.Duration)
+
+	// slaWarning is called when a task outlives its SLA warning, see
+	// OnSLAWarning.
+	slaWarning func(index int, elapsed time.Duration)
+
+	// onComplete and onTerminate are called as Start returns, see
+	// OnComplete and OnTerminate.
+	onComplete  func(RunReport)
+	onTerminate func(RunReport, error)
+
+	// workerInit builds the value of each worker, see WithWorkerInit.
+	// workerValues holds the values built so far, by worker id, guarded
+	// by m.
+	workerInit   func(workerID int) any
+	workerValues map[int]any
+
+	// workerStart and workerExit are called as each worker starts and
+	// exits.
+	workerStart, workerExit func(id int)
+
+	// sampleInterval and sampleQueue configure the queue depth sampling
+	// set with OnQueueSample.
+	sampleInterval time.Duration
+	sampleQueue    func(depth int)
+
+	// queueDrained is called when a worker takes the last pending task,
+	// see OnQueueDrained.
+	queueDrained func()
+
+	// paused holds back the dispatch of tasks, see Pause.
+	paused bool
+
+	// requireAllStarted reports the tasks left unstarted by a timeout,
+	// see WithRequireAllStarted.
+	requireAllStarted bool
+
+	// softDeadline is the time after which no task starts and softGrace
+	// how long the running tasks then have to finish, see
+	// WithSoftDeadline.
+	softDeadline time.Time
+	softGrace    time.Duration
+
+	// cutoff is the error the run ends with once the dispatch has been
+	// cut off, by the soft deadline or by an interrupt under
+	// WithDoubleInterruptForce, nil until then.
+	cutoff error
+
+	// forceWindow is the time within which a second interrupt aborts the
+	// run, see WithDoubleInterruptForce.
+	forceWindow time.Duration
+
+	// healthCheck is polled every healthInterval, unhealthy holds back
+	// the dispatch of tasks while it fails and healthGrace is how long it
+	// may fail before the run ends, see WithHealthCheck.
+	healthCheck    func() bool
+	healthInterval time.Duration
+	healthGrace    time.Duration
+	unhealthy      bool
+
+	// outcome is the terminal reason of the run.
+	outcome Outcome
+
+	// trace records the dispatch decisions, see RecordTrace. replay is
+	// the trace forced on the dispatch and replayed the number of its
+	// steps done, see WithReplayTrace.
+	trace    *Trace
+	replay   *Trace
+	replayed int
+
+	// dispatched holds the indices of the tasks in the order they were
+	// handed to the workers, guarded by m.
+	dispatched []int
+
+	// timeline holds the phase timestamps of the run.
+	timeline RunReport
+
+	// succeeded, failed and finished count the tasks that returned
+	// without error, returned an error, and left the queue in any way.
+	// The outstanding work is len(tasks) - finished.
+	succeeded, failed, finished int
+}
+
+// completion is sent on the complete channel when a worker has run a task,
+// or with a nil task when the worker exits. end is when the task returned,
+// which the master goroutine may only get to later.
+type completion struct {
+	t   *task
+	res TaskResult
+	end time.Time
 }
 
 // ErrTimeout is returned when a value is received on the timeout channel.
+// It only ever reports the timeout of the run itself: a task running past
+// its own timeout is recorded with ErrTaskTimeout in its result and does not
+// by itself make Start fail.
 var ErrTimeout = errors.New("received timeout")
 
-// ErrInterrupt is returned when an event from the OS is received.
+// ErrDeadline is returned when the deadline set with WithDeadline passes
+// before the timeout does.
+var ErrDeadline = errors.New("deadline exceeded")
+
+// ErrInterrupt is returned when an interrupt or termination signal is
+// received from the OS, see ReceivedSignal.
 var ErrInterrupt = errors.New("received interrupt")
 
+// ErrWorkBudgetExceeded is returned when the cumulative duration of the
+// completed tasks exceeds the budget set with WithTotalWorkBudget.
+var ErrWorkBudgetExceeded = errors.New("work budget exceeded")
+
+// ErrQuorumNotMet is returned when too many tasks failed for the quorum set
+// with WithQuorum to be reached.
+var ErrQuorumNotMet = errors.New("quorum not met")
+
+// ErrTaskPanicked is recorded for a task whose panic was recovered.
+var ErrTaskPanicked = errors.New("task panicked")
+
+// ErrTaskSkipped is recorded for a task added with AddEstimated that was
+// not started because it was not expected to finish in the time left, and
+// for a task dropped by the task filter.
+var ErrTaskSkipped = errors.New("task skipped")
+
+// ErrTaskNotRun is returned by WaitTask when the run ended before the task
+// finished.
+var ErrTaskNotRun = errors.New("task did not run")
+
+// ErrStopped is returned when the run is ended by Stop.
+var ErrStopped = errors.New("runner stopped")
+
+// ErrRunInProgress is returned by Reset when the previous run has not
+// finished yet.
+var ErrRunInProgress = errors.New("run in progress")
+
+// ErrAborted is returned when the run is ended by Abort.
+var ErrAborted = errors.New("runner aborted")
+
 // New returns a new ready-to-use Runner.
 func New(d time.Duration, numberOfWorker int) *Runner {
-	return &Runner{
-		interrupt:      make(chan os.Signal, 1),
-		complete:       make(chan error),
-		timeout:        time.After(d),
-		completeMain:   make(chan error),
-		numberOfWorker: numberOfWorker,
+	r := &Runner{
+		interrupt:       make(chan os.Signal, 1),
+		complete:        make(chan completion),
+		timeoutDuration: d,
+		completeMain:    make(chan error, 1),
+		done:            make(chan struct{}),
+		numberOfWorker:  numberOfWorker,
+		alive:           make([]bool, numberOfWorker),
+		busy:            make([]*task, numberOfWorker),
+		notifier:        osNotifier{},
 	}
+	r.cond = sync.NewCond(&r.m)
+	r.ctx, r.cancelRun = context.WithCancel(context.Background())
+	return r
+}
+
+// NewWithOptions returns a new ready-to-use Runner configured with opts.
+func NewWithOptions(d time.Duration, numberOfWorker int, opts ...Option) *Runner {
+	r := New(d, numberOfWorker)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run runs tasks on a new Runner with numberOfWorker workers and a timeout
+// of d, and returns the result of Start.
+func Run(d time.Duration, numberOfWorker int, tasks ...func(int)) error {
+	r := New(d, numberOfWorker)
+	r.Add(tasks...)
+	return r.Start()
+}
+
+// AddWithPriority attaches a task dispatched according to p. Tasks added
+// without a priority are Normal.
+func (r *Runner) AddWithPriority(p Priority, fn func(int)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.pushWithPriority(p, fn)
 }
 
 // Add attaches tasks to the Runner. A task is a function that
-// takes an int ID.
+// takes an int ID. Tasks may be added while the run is in progress, the
+// run only ends once no task is left pending or running.
 func (r *Runner) Add(tasks ...func(int)) {
-	r.tasks = append(r.tasks, tasks...)
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		r.push(fn)
+	}
+}
+
+// AddFallible attaches tasks that can fail. A task that returns a non-nil
+// error counts as failed, which matters for WithQuorum.
+func (r *Runner) AddFallible(tasks ...func(int) error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		r.push(nil).efn = func(id int) (any, error) {
+			return nil, fn(id)
+		}
+	}
+}
+
+// push registers fn as a new pending Normal task, r.m must be held.
+func (r *Runner) push(fn func(int)) *task {
+	return r.pushWithPriority(Normal, fn)
+}
+
+// pushWithPriority registers fn as a new pending task of priority p, r.m
+// must be held.
+func (r *Runner) pushWithPriority(p Priority, fn func(int)) *task {
+	t := r.alloc(p, fn)
+	r.queue.push(t)
+	r.wakeFor(t)
+	if r.preemption && p == Critical {
+		r.preempt()
+	}
+	return t
+}
+
+// alloc registers fn as a new task of priority p without queueing it, r.m
+// must be held.
+func (r *Runner) alloc(p Priority, fn func(int)) *task {
+	// tasks are carved out of blocks so that registering many of them
+	// does not cost one allocation each.
+	if len(r.chunk) == 0 {
+		r.chunk = make([]task, taskChunkSize)
+	}
+	t := &r.chunk[0]
+	r.chunk = r.chunk[1:]
+	t.index = len(r.tasks)
+	t.fn = fn
+	t.priority = p
+	r.tasks = append(r.tasks, t)
+	return t
+}
+
+// wakeFor gets a worker to pick up the new task t, spawning one if idle
+// workers have exited, r.m must be held.
+func (r *Runner) wakeFor(t *task) {
+	if t.ext().affine || r.replay != nil {
+		r.cond.Broadcast()
+	} else {
+		r.cond.Signal()
+	}
+	if r.burstWorker {
+		r.burst()
+	}
+	if r.idleTimeout <= 0 || !r.dispatching {
+		return
+	}
+	if x := t.ext(); x.affine {
+		if !r.alive[x.worker] {
+			r.spawn(x.worker)
+		}
+	} else if r.idle == 0 {
+		r.spawnIdle(1)
+	}
+}
+
+// spawnIdle spawns up to n workers in place of those that exited on their
+// idle timeout, r.m must be held.
+func (r *Runner) spawnIdle(n int) {
+	for id := 0; id < r.numberOfWorker && n > 0; id++ {
+		if !r.alive[id] {
+			r.spawn(id)
+			n--
+		}
+	}
+}
+
+// spawn starts worker id, r.m must be held.
+func (r *Runner) spawn(id int) {
+	r.alive[id] = true
+	r.live++
+	r.goroutines++
+	done := r.done
+	r.goTracked(func() { r.worker(id, done) })
+}
+
+// goTracked runs fn on a new goroutine counted by GoroutineCount.
+func (r *Runner) goTracked(fn func()) {
+	r.spawned.Add(1)
+	go func() {
+		defer r.spawned.Add(-1)
+		fn()
+	}()
+}
+
+// GoroutineCount returns the number of goroutines the runner has started
+// that have not returned yet: the workers, the goroutine collecting their
+// results, the goroutines polling the queue depth, the signals and the
+// health check when those are enabled, and those of the sliced tasks. It
+// drops back to zero shortly after a run has ended and its tasks have
+// returned.
+func (r *Runner) GoroutineCount() int {
+	return int(r.spawned.Load())
+}
+
+// grow makes room for n more tasks in s.
+func grow(s []*task, n int) []*task {
+	if cap(s)-len(s) >= n {
+		return s
+	}
+	g := make([]*task, len(s), 2*cap(s)+n)
+	copy(g, s)
+	return g
+}
+
+// OnQueueSample calls fn with the number of pending tasks every interval
+// while the run is in progress. It must be called before Start.
+func (r *Runner) OnQueueSample(interval time.Duration, fn func(depth int)) {
+	r.sampleInterval = interval
+	r.sampleQueue = fn
+}
+
+// OnQueueDrained registers fn to be called when a worker takes the last
+// pending task while the run is still in progress, so that a producer can
+// top up the queue with Add before the run runs out of work. The worker
+// calls fn before it runs the task it took, so the run cannot end while fn
+// is running. It must be called before Start.
+func (r *Runner) OnQueueDrained(fn func()) {
+	r.queueDrained = fn
+}
+
+// OnRetry registers fn to be called before each retry of a failed task with
+// the index of the task, the number of the attempt that failed, its error
+// and the delay before the next attempt. It is called on the worker running
+// the task. It must be called before Start.
+func (r *Runner) OnRetry(fn func(index, attempt int, err error, nextDelay time.Duration)) {
+	r.onRetry = fn
+}
+
+// OnSLAWarning registers fn to be called with the index of each task added
+// with AddWithSLA that is still running once its warning threshold, passed
+// as elapsed, has gone by. It is called on a goroutine of its own while
+// the task keeps running. It must be called before Start.
+func (r *Runner) OnSLAWarning(fn func(index int, elapsed time.Duration)) {
+	r.slaWarning = fn
+}
+
+// OnSlowTask registers fn to be called with the index and duration of each
+// task that ran for longer than threshold, retries included, once it has
+// finished. It must be called before Start.
+func (r *Runner) OnSlowTask(threshold time.Duration, fn func(index int, d time.Duration)) {
+	r.slowAfter = threshold
+	r.slowTask = fn
+}
+
+// OnComplete registers fn to be called with the report of the run when it
+// completes, just before Start returns nil. It is not called when the run
+// ends with an error, see OnTerminate. It must be called before Start.
+func (r *Runner) OnComplete(fn func(RunReport)) {
+	r.onComplete = fn
+}
+
+// OnTerminate registers fn to be called with the report of the run and the
+// error Start is about to return when the run ends with an error, such as
+// a timeout or an interrupt. It is not called when the run completes, see
+// OnComplete. It must be called before Start.
+func (r *Runner) OnTerminate(fn func(RunReport, error)) {
+	r.onTerminate = fn
+}
+
+// OnWorkerStart registers fn to be called with the id of each worker as it
+// starts. It must be called before Start.
+func (r *Runner) OnWorkerStart(fn func(id int)) {
+	r.workerStart = fn
+}
+
+// OnWorkerExit registers fn to be called with the id of each worker as it
+// exits, after its last task has returned. When Start returns because
+// every task has run, every exit has been reported. It must be called
+// before Start.
+func (r *Runner) OnWorkerExit(fn func(id int)) {
+	r.workerExit = fn
+}
+
+// Prewarm spawns the workers ahead of Start, so that they are already
+// parked when it is called and pick up the first tasks without delay. Start
+// only spawns the workers that are not already running, and calling Prewarm
+// more than once or after Start has no effect.
+func (r *Runner) Prewarm() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for id := 0; id < r.numberOfWorker; id++ {
+		if !r.alive[id] {
+			r.spawn(id)
+		}
+	}
 }
 
 // Start runs all tasks and monitors channel events.
 func (r *Runner) Start() error {
+	completed, err := r.restore()
+	if err != nil {
+		return err
+	}
+	usage, usageErr := processUsage()
+	r.m.Lock()
+	r.skip(completed)
+	returned, exited := make(chan struct{}), make(chan struct{})
+	r.returned, r.exited = returned, exited
+	done := r.done
+	r.dispatching = true
+	r.advanceStage()
+	queued := r.queue.len()
+	r.timeline = RunReport{DispatchStart: time.Now()}
+	r.runDeadline = r.timeline.DispatchStart.Add(r.timeoutDuration)
+	if !r.deadline.IsZero() && r.deadline.Before(r.runDeadline) {
+		r.runDeadline = r.deadline
+	}
+	r.m.Unlock()
+	defer close(returned)
+	defer func() { r.measureUsage(usage, usageErr) }()
+
 	// We want to receive all interrupt based signals.
-	signal.Notify(r.interrupt, os.Interrupt)
+	if !r.ignoreSignals {
+		r.notifier.Notify(r.interrupt, os.Interrupt, syscall.SIGTERM)
+		defer r.notifier.Stop(r.interrupt)
+	}
+
+	r.log("run started", slog.Int("tasks", queued), slog.Int("workers", r.numberOfWorker))
+
+	if r.synchronous {
+		err := r.runSync(done)
+		r.closeErrors()
+		close(exited)
+		return r.conclude(r.incomplete(err))
+	}
+
+	// The timeout runs from now on, the deadline is absolute.
+	r.timeout = time.After(r.timeoutDuration)
+	var deadline <-chan time.Time
+	if !r.deadline.IsZero() {
+		deadline = time.After(time.Until(r.deadline))
+	}
+	var soft, grace <-chan time.Time
+	if !r.softDeadline.IsZero() {
+		soft = time.After(time.Until(r.softDeadline))
+	}
+
+	if r.sampleQueue != nil {
+		r.goTracked(func() { r.sample(done) })
+	}
+	if len(r.signalHandlers) > 0 && !r.ignoreSignals {
+		c := r.notifySignals()
+		r.goTracked(func() { r.handleSignals(c, done) })
+	}
+	if r.healthCheck != nil {
+		// probe before dispatch starts, so that no task is started
+		// against a dependency that is already down
+		healthy := r.probeHealth()
+		r.goTracked(func() { r.checkHealth(done, healthy) })
+	}
+	if r.checkpointStore != nil {
+		r.goTracked(func() { r.checkpoint(done) })
+	}
 
 	// Run the different tasks on a different goroutine.
 	r.run()
 	// spin up the master GOR
-	go func() {
-		// check if all the task as been precessed
-		completedTask := 0
-		for range r.complete {
-			completedTask++
-			if completedTask == r.numberOfWorker {
-				close(r.complete)
-				r.completeMain <- nil
-				return
+	r.goTracked(func() {
+		defer close(exited)
+		defer r.closeErrors()
+		// record the tasks as they finish until every worker has exited,
+		// which only happens once no work is outstanding or the run ended.
+		for c := range r.complete {
+			if c.t != nil {
+				r.finish(c.t, c.res, c.end)
+				continue
+			}
+			r.m.Lock()
+			r.goroutines--
+			if r.goroutines > 0 || r.respawnable() {
+				r.m.Unlock()
+				continue
 			}
+			r.dispatching = false
+			r.timeline.ShutdownComplete = time.Now()
+			r.m.Unlock()
+			r.end(r.finalErr())
+			return
 		}
-	}()
+	})
+	var interruptedAt time.Time
+	for {
+		select {
+		// Signaled when processing is done.
+		case err = <-r.completeMain:
+
+		// Signaled when an interrupt event is sent. Ending the run
+		// cancels the context of the running context-aware tasks
+		// right away. Under WithDoubleInterruptForce the running tasks
+		// are drained instead, unless a second interrupt follows
+		// quickly.
+		case sig := <-r.interrupt:
+			if r.forceWindow <= 0 {
+				r.interrupted(sig)
+				err = <-r.completeMain
+				break
+			}
+			if !interruptedAt.IsZero() && time.Since(interruptedAt) <= r.forceWindow {
+				r.Abort()
+				err = <-r.completeMain
+				break
+			}
+			interruptedAt = time.Now()
+			r.recordSignal(sig)
+			r.cutOff(ErrInterrupt)
+			continue
+
+		// Signaled when we run out of time.
+		case <-r.timeout:
+			r.end(ErrTimeout)
+			err = <-r.completeMain
+			if r.timeoutDrain {
+				<-exited
+			}
+
+		// Signaled when the deadline passes.
+		case <-deadline:
+			r.end(ErrDeadline)
+			err = <-r.completeMain
+			if r.timeoutDrain {
+				<-exited
+			}
+
+		// Signaled when the soft deadline passes, the running tasks
+		// are given the grace period to finish.
+		case <-soft:
+			soft = nil
+			r.cutOff(ErrTimeout)
+			grace = time.After(r.softGrace)
+			continue
+
+		// Signaled when the grace period after the soft deadline is
+		// over, the running tasks are abandoned.
+		case <-grace:
+			r.end(ErrTimeout)
+			err = <-r.completeMain
+		}
+		return r.conclude(r.incomplete(err))
+	}
+}
+
+// WaitTimeout waits up to d for the run to finish and returns the error
+// Start returned for it, and true. It returns false if the run is still in
+// progress after d, or has not been started yet, so that the caller can
+// poll.
+func (r *Runner) WaitTimeout(d time.Duration) (error, bool) {
+	r.m.Lock()
+	returned := r.returned
+	r.m.Unlock()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	if returned == nil {
+		<-timer.C
+		return nil, false
+	}
 	select {
-	// Signaled when processing is done.
-	case err := <-r.completeMain:
-		// id the err is ErrInterrupt => tell all the running workers to terminate
-		return err
+	case <-returned:
+		r.m.Lock()
+		defer r.m.Unlock()
+		return r.result, true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// ReceivedSignal returns the signal that interrupted the run, so that the
+// caller can tell SIGINT from SIGTERM when Start returns ErrInterrupt. It
+// returns nil when the run was not interrupted.
+func (r *Runner) ReceivedSignal() os.Signal {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.received
+}
+
+// interrupted records sig and ends the run with ErrInterrupt.
+func (r *Runner) interrupted(sig os.Signal) {
+	r.recordSignal(sig)
+	r.end(ErrInterrupt)
+}
+
+// recordSignal records sig as the signal that interrupted the run, unless
+// one was already.
+func (r *Runner) recordSignal(sig os.Signal) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.received == nil {
+		r.received = sig
+	}
+}
 
-	// Signaled when we run out of time.
-	case <-r.timeout:
-		return ErrTimeout
+// conclude saves the last checkpoint, logs the end of the run with its
+// result err, calls the OnComplete or OnTerminate hook and returns err.
+func (r *Runner) conclude(err error) error {
+	if r.checkpointStore != nil {
+		r.saveCheckpoint()
 	}
+	r.m.Lock()
+	r.result = err
+	r.m.Unlock()
+	if err != nil {
+		r.log("run stopped", slog.String("error", err.Error()))
+		if r.onTerminate != nil {
+			r.onTerminate(r.Report(), err)
+		}
+	} else {
+		r.log("run completed")
+		if r.onComplete != nil {
+			r.onComplete(r.Report())
+		}
+	}
+	return err
+}
+
+// Pause holds back the dispatch of new tasks until Resume is called. Tasks
+// already running are not affected, and a paused run does not complete
+// until it is resumed, though it can still time out.
+func (r *Runner) Pause() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.paused = true
+}
+
+// Resume lets the dispatch of tasks held back by Pause continue.
+func (r *Runner) Resume() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.paused = false
+	r.cond.Broadcast()
+	r.respawn()
+}
+
+// togglePause pauses a running dispatch or resumes a paused one.
+func (r *Runner) togglePause() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.paused = !r.paused
+	if !r.paused {
+		r.cond.Broadcast()
+		r.respawn()
+	}
+}
+
+// respawn spawns workers for the pending tasks when idle workers have
+// exited meanwhile, r.m must be held.
+func (r *Runner) respawn() {
+	if r.idleTimeout > 0 && r.dispatching {
+		r.spawnIdle(r.queue.len() - r.idle)
+	}
+}
+
+// respawnable reports whether work is still due while every worker has
+// exited on its idle timeout, so that new ones will be spawned for it,
+// r.m must be held.
+func (r *Runner) respawnable() bool {
+	if r.idleTimeout <= 0 || !r.dispatching || r.cutoff != nil {
+		return false
+	}
+	if r.workBudget > 0 && r.workSpent > r.workBudget {
+		return false
+	}
+	return r.finished < len(r.tasks) || (r.source != nil && !r.sourceDrained)
+}
+
+// StartWith adds tasks to those already attached and runs them all.
+func (r *Runner) StartWith(tasks ...func(int)) error {
+	r.Add(tasks...)
+	return r.Start()
+}
+
+// Done returns a channel that is closed when the run ends, whatever the
+// reason, so that the run can be waited for in a select. It may be called
+// before Start. A run started by Restart has a new channel.
+func (r *Runner) Done() <-chan struct{} {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.done
+}
+
+// Stop ends the run: no new task is started and Start returns ErrStopped.
+// Tasks already running are left to finish in the background.
+func (r *Runner) Stop() {
+	r.end(ErrStopped)
+}
+
+// Abort ends the run at once: the pending tasks are discarded and never
+// run, the context of the running context-aware tasks is canceled and
+// Start returns ErrAborted without waiting for the running tasks.
+func (r *Runner) Abort() {
+	r.end(ErrAborted)
+	r.m.Lock()
+	defer r.m.Unlock()
+	for _, t := range append(append(r.queue.clear(), r.unhold()...), r.unchain()...) {
+		t.close(r)
+	}
+}
+
+// Restart stops the current run, waits for its running tasks to finish,
+// discards the tasks it left pending and starts a new run of tasks. It
+// returns the result of the new run. The Start call of the stopped run
+// returns ErrStopped.
+func (r *Runner) Restart(tasks ...func(int)) error {
+	r.m.Lock()
+	returned, exited := r.returned, r.exited
+	r.m.Unlock()
+	if returned != nil {
+		r.Stop()
+		<-returned
+		<-exited
+	}
+	r.reset()
+	r.Add(tasks...)
+	return r.Start()
+}
+
+// Reset clears the tasks and the state of the previous run so that the
+// runner can be reused with new tasks. It returns ErrRunInProgress, and
+// leaves the runner untouched, while Start has not returned or tasks of the
+// previous run are still running. See Restart to stop a run and start
+// another.
+func (r *Runner) Reset() error {
+	r.m.Lock()
+	returned, exited, workers := r.returned, r.exited, r.goroutines
+	r.m.Unlock()
+	if returned != nil {
+		select {
+		case <-returned:
+		default:
+			return ErrRunInProgress
+		}
+		if workers > 0 {
+			return ErrRunInProgress
+		}
+		// the master goroutine is about to return
+		<-exited
+	}
+	r.reset()
+	return nil
+}
+
+// reset clears the tasks and the state of the previous run so that the
+// runner can start afresh. No goroutine of the previous run may be alive.
+func (r *Runner) reset() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = nil
+	r.queue = queue{}
+	r.held, r.heldCount = nil, 0
+	r.sequences, r.sequenced = nil, 0
+	r.paused = false
+	r.cutoff = nil
+	r.unhealthy = false
+	r.received = nil
+	r.outcome = Unfinished
+	r.sourceDrained = false
+	r.workSpent = 0
+	r.retries = 0
+	r.reportedCPU = 0
+	r.failures = nil
+	r.dispatched = nil
+	r.replayed = 0
+	r.ring, r.next, r.dropped = nil, 0, 0
+	r.succeeded, r.failed, r.finished = 0, 0, 0
+	r.done = make(chan struct{})
+	r.completeMain = make(chan error, max(r.mainBuffer, 1))
+	r.endOnce = sync.Once{}
+	r.ctx, r.cancelRun = context.WithCancel(context.Background())
+	// a signal the previous run did not get to must not end the next one
+	for len(r.interrupt) > 0 {
+		<-r.interrupt
+	}
+	r.returned, r.exited = nil, nil
+	r.result = nil
+	r.usage, r.usageErr = Rusage{}, nil
 }
 
 // run executes each registered task.
 func (r *Runner) run() error {
-	for id := 0; id < r.numberOfWorker; id++ {
-		// spin up the worker GORs to Execute the registered task.
-		go func(i int) {
-			//get the task
-			task, ok := r.getTask()
-			for ok {
-				// Check for an interrupt signal from the OS.
-				if r.gotInterrupt() {
-					r.completeMain <- ErrInterrupt
-					return
-				}
-				// run the task
-				task(i)
-				task, ok = r.getTask()
-			}
-			r.complete <- nil
-		}(id)
+	r.m.Lock()
+	defer r.m.Unlock()
+	n := r.numberOfWorker
+	if r.rampUp > 0 && n > 1 {
+		// the other workers come online one step apart
+		n = 1
+		done := r.done
+		r.goTracked(func() { r.rampUpWorkers(done) })
+	}
+	for id := 0; id < n; id++ {
+		// spin up the worker GORs to Execute the registered task,
+		// unless they were prewarmed.
+		if !r.alive[id] {
+			r.spawn(id)
+		}
+	}
+	// wake up the prewarmed workers
+	r.cond.Broadcast()
+	if r.burstWorker {
+		r.burst()
 	}
 
 	return nil
 }
 
-// gotInterrupt verifies if the interrupt signal has been issued.
-func (r *Runner) gotInterrupt() bool {
-	select {
-	// Signaled when an interrupt event is sent.
-	case <-r.interrupt:
-		r.terminate = true
-		// Stop receiving any further signals.
-		signal.Stop(r.interrupt)
-		return true
+// rampUpWorkers spawns the workers after the first one, rampUp apart, as
+// long as the run is in progress. done is the done channel of that run.
+func (r *Runner) rampUpWorkers(done <-chan struct{}) {
+	for id := 1; id < r.numberOfWorker; id++ {
+		if !r.sleep(done, r.rampUp) {
+			return
+		}
+		r.m.Lock()
+		if !r.dispatching {
+			r.m.Unlock()
+			return
+		}
+		if !r.alive[id] {
+			r.spawn(id)
+		}
+		r.m.Unlock()
+	}
+}
+
+// worker runs tasks on worker i until no work is outstanding or the run
+// ends. done is the done channel of the run it was spawned for, so that a
+// later run replacing it does not affect the worker.
+func (r *Runner) worker(i int, done <-chan struct{}) {
+	if r.workerStart != nil {
+		r.workerStart(i)
+	}
+	if r.workerInit != nil {
+		r.initWorker(i)
+	}
+	for {
+		// hold off while the heap is over the memory gate
+		if r.maxHeap > 0 && !r.waitForMemory(done) {
+			break
+		}
+		//get the task
+		t, ok := r.getTask(i)
+		if !ok {
+			break
+		}
+		if r.startJitter > 0 && !r.jitter(done) {
+			// the run ended before t started, it is pending again
+			r.m.Lock()
+			r.release(i)
+			r.queue.push(t)
+			r.m.Unlock()
+			break
+		}
+		// run the task
+		r.log("task started", slog.Int("task", t.index), slog.Int("worker", i))
+		res := r.execute(t, i, done)
+		if res.Err == errYielded {
+			// a sliced task gave the worker up for the pending tasks
+			r.reschedule(t, res.Duration)
+			continue
+		}
+		if r.requeuePreempted(t) {
+			continue
+		}
+		r.spend(res.Duration)
+		r.complete <- completion{t: t, res: res, end: time.Now()}
+		r.logFinished(t, i, res)
+		if r.yield {
+			// give the other goroutines of the process a turn
+			runtime.Gosched()
+		}
+	}
+	if r.workerExit != nil {
+		r.workerExit(i)
+	}
+	r.complete <- completion{}
+}
+
+// initWorker builds the value of worker id, unless an earlier worker with
+// the same id already did.
+func (r *Runner) initWorker(id int) {
+	r.m.Lock()
+	_, ok := r.workerValues[id]
+	r.m.Unlock()
+	if ok {
+		return
+	}
+	v := r.workerInit(id)
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.workerValues == nil {
+		r.workerValues = make(map[int]any)
+	}
+	r.workerValues[id] = v
+}
 
-		// Continue running as normal.
-	default:
-		// check if ternimate
-		if r.terminate {
-			return true
+// WorkerValue returns the value built by the WithWorkerInit function for
+// the worker with the given id, or nil if there is none. Tasks are handed
+// the id of the worker running them, so that they can get at its value.
+func (r *Runner) WorkerValue(id int) any {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.workerValues[id]
+}
+
+// AddWithWorkerValue attaches tasks that are handed the value built by the
+// WithWorkerInit function for the worker running them, along with its id.
+// The value is nil without WithWorkerInit.
+func (r *Runner) AddWithWorkerValue(tasks ...func(id int, v any)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		r.push(func(id int) {
+			fn(id, r.WorkerValue(id))
+		})
+	}
+}
+
+// logFinished logs the result res of t, run on worker id.
+func (r *Runner) logFinished(t *task, id int, res TaskResult) {
+	if r.logger == nil {
+		return
+	}
+	outcome := "completed"
+	if res.Cached {
+		outcome = "cached"
+	} else if res.Skipped {
+		outcome = "skipped"
+	} else if errors.Is(res.Err, ErrTaskPanicked) {
+		outcome = "panicked"
+	} else if res.Err != nil {
+		outcome = "failed"
+	}
+	r.log("task finished", slog.Int("task", t.index), slog.Int("worker", id),
+		slog.String("outcome", outcome), slog.Duration("duration", res.Duration))
+}
+
+// sample reports the queue depth on every tick until done is closed.
+func (r *Runner) sample(done <-chan struct{}) {
+	ticker := time.NewTicker(r.sampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.m.Lock()
+			depth := r.queue.len()
+			r.m.Unlock()
+			r.sampleQueue(depth)
+		case <-done:
+			return
 		}
+	}
+}
+
+// execute runs t on worker id and returns its result. Keyed tasks are
+// served from the result cache when it holds their key, and tasks that are
+// not expected to finish before the run does are skipped. A task that fails
+// is run again as the retry policy allows.
+func (r *Runner) execute(t *task, id int, done <-chan struct{}) (res TaskResult) {
+	res.Index = t.index
+	res.Worker = id
+	x := t.ext()
+	if x.keyed && r.cache != nil {
+		if cached, ok := r.cache.Get(x.key); ok {
+			res.Value, res.Err, res.Cached = cached.Value, cached.Err, true
+			return res
+		}
+	}
+	if x.estimate > 0 && time.Until(r.runDeadline) < x.estimate {
+		res.Err, res.Skipped = ErrTaskSkipped, true
+		return res
+	}
+	// a sliced task resuming after a yield was filtered when it started
+	if r.filter != nil && (x.slice == nil || !x.slice.parked) && !r.filterTask(t) {
+		res.Err, res.Skipped = ErrTaskSkipped, true
+		return res
+	}
+	var before runtime.MemStats
+	if r.allocProfiling {
+		runtime.ReadMemStats(&before)
+	}
+	policy := r.retry
+	if x.retry != nil {
+		policy = *x.retry
+	}
+	start := time.Now()
+	for t.attempt = 1; ; t.attempt++ {
+		var panicked bool
+		res.Value, panicked, res.Err = r.try(t, id)
+		if res.Err == nil || res.Err == errYielded || (panicked && !r.retryOnPanic) || t.attempt >= policy.MaxAttempts {
+			break
+		}
+		if !r.retryable(res.Err) {
+			break
+		}
+		if !r.takeRetry() {
+			break
+		}
+		delay := policy.delay(t.attempt)
+		if r.onRetry != nil {
+			r.onRetry(t.index, t.attempt, res.Err, delay)
+		}
+		if !r.sleep(done, delay) {
+			break
+		}
+	}
+	res.Duration = time.Since(start)
+	if r.allocProfiling {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		res.AllocBytes = after.TotalAlloc - before.TotalAlloc
+		res.Allocs = after.Mallocs - before.Mallocs
+	}
+	if x.slice != nil {
+		res.Duration += x.slice.elapsed
+	}
+	res.Attempts = t.attempt
+	if x.keyed && r.cache != nil && res.Err == nil {
+		r.cache.Set(x.key, res)
+	}
+	return res
+}
+
+// filterTask passes t through the task filter, replacing its function with
+// the one returned, and reports false when the filter drops it. The value
+// and error of a value-producing task are kept as long as the filter runs
+// the function it was handed.
+func (r *Runner) filterTask(t *task) bool {
+	var v any
+	var err error
+	fn, efn := t.fn, t.efn
+	orig := func(id int) {
+		if efn != nil {
+			v, err = efn(id)
+		} else {
+			fn(id)
+		}
+	}
+	filtered := r.filter(t.index, orig)
+	if filtered == nil {
 		return false
 	}
+	t.fn, t.efn = nil, func(id int) (any, error) {
+		v, err = nil, nil
+		filtered(id)
+		return v, err
+	}
+	return true
 }
 
-// getTask
-func (r *Runner) getTask() (task func(int), found bool) {
+// try makes one attempt at running t on worker id. Unless panic recovery
+// is turned off and panics are not retried, a panic in the task is
+// recovered, handed to the handler or else logged, and turned into an error.
+func (r *Runner) try(t *task, id int) (v any, panicked bool, err error) {
+	if r.panicHandler != nil || r.retryOnPanic || !r.noRecover {
+		defer func() {
+			if p := recover(); p != nil {
+				if r.panicHandler != nil {
+					r.panicHandler(t.index, p, debug.Stack())
+				} else {
+					r.logPanic(t, p, debug.Stack())
+				}
+				v, panicked, err = nil, true, fmt.Errorf("%w: %v", ErrTaskPanicked, p)
+			}
+		}()
+	}
+	v, err = t.run(id)
+	return v, false, err
+}
+
+// getTask takes the next pending task that worker id may run off the queue,
+// pulling one from the task source when the queue is empty. When there is
+// none it waits for one as long as work is outstanding, since running tasks
+// may still add more.
+func (r *Runner) getTask(id int) (t *task, found bool) {
 	// secure this operation with lock
 	r.m.Lock()
 	defer r.m.Unlock()
-	//TODO: fetch the task form the task queue
-	for i, value := range r.tasks {
-		if value == nil {
+	// the worker exits when it gets no task
+	defer func() {
+		if !found {
+			r.alive[id] = false
+			r.live--
+		}
+	}()
+	r.release(id)
+	var idleSince time.Time
+	for {
+		// no new task starts once the run has ended
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+		// prewarmed workers wait for Start
+		if !r.dispatching {
+			if !r.wait(&idleSince) {
+				return
+			}
 			continue
-		} else {
-			task = value
-			found = true
-			// set the index to nil
-			r.tasks[i] = nil
-			break
 		}
+		// no new task starts once the work budget is used up or the
+		// soft deadline has passed
+		if (r.workBudget > 0 && r.workSpent > r.workBudget) || r.cutoff != nil {
+			return
+		}
+		// a paused runner hands out nothing until resumed, nor does one
+		// whose dependency is unhealthy
+		if r.paused || r.unhealthy {
+			if !r.wait(&idleSince) {
+				return
+			}
+			continue
+		}
+		if r.standby(id) && r.queue.len() <= r.burstDepth {
+			// the standby worker stands down once the burst is over
+			return
+		}
+		if t = r.take(id); t != nil {
+			r.handOut(t, id)
+			if r.queueDrained != nil && r.queue.len() == 0 {
+				// t is outstanding until it has run, so the run is not
+				// over while the producer tops up the queue.
+				r.m.Unlock()
+				r.queueDrained()
+				r.m.Lock()
+			}
+			return t, true
+		}
+		// pull from the source, one worker at a time and without holding
+		// the lock, since Next may block.
+		if r.source != nil && !r.sourceDrained && !r.pulling {
+			r.pulling = true
+			r.m.Unlock()
+			fn, ok := r.source.Next()
+			r.m.Lock()
+			r.pulling = false
+			if ok {
+				r.push(fn)
+			} else {
+				r.sourceDrained = true
+				r.cond.Broadcast()
+			}
+			continue
+		}
+		if r.finished == len(r.tasks) && (r.source == nil || r.sourceDrained) {
+			return
+		}
+		if !r.wait(&idleSince) {
+			return
+		}
+	}
+}
+
+// wait blocks the calling worker until workers are woken up, r.m must be
+// held. With an idle timeout, it reports false once the worker has been
+// idle for that long since idleSince, and the worker is to exit.
+func (r *Runner) wait(idleSince *time.Time) bool {
+	if r.idleTimeout <= 0 {
+		r.idle++
+		r.cond.Wait()
+		r.idle--
+		return true
+	}
+	if idleSince.IsZero() {
+		*idleSince = time.Now()
+	}
+	left := r.idleTimeout - time.Since(*idleSince)
+	if left <= 0 {
+		return false
+	}
+	timer := time.AfterFunc(left, r.cond.Broadcast)
+	r.idle++
+	r.cond.Wait()
+	r.idle--
+	timer.Stop()
+	return true
+}
+
+// finish records the result of t, feeds the aggregators and ends the run
+// early if the outcome settles the quorum, hits the concurrent failure limit
+// or satisfies the completion predicate. end is when t returned. It is only
+// called from the master goroutine.
+func (r *Runner) finish(t *task, res TaskResult, end time.Time) {
+	r.m.Lock()
+	now := time.Now()
+	r.timeline.LastTaskEnd = now
+	if r.first != nil {
+		r.first <- res
+		r.first = nil
+	}
+	if r.ringSize > 0 {
+		r.keep(res)
+		// the value is only retained by the ring
+		res.Value = nil
+	}
+	t.result = res
+	t.ran = true
+	t.ended = now
+	failed := r.failure(res)
+	if failed {
+		r.failed++
+	} else if res.Err == nil {
+		r.succeeded++
+	}
+	t.close(r)
+	settled, err := false, error(nil)
+	if r.quorum > 0 {
+		if r.succeeded >= r.quorum {
+			settled = true
+		} else if r.succeeded+len(r.tasks)-r.finished < r.quorum {
+			settled, err = true, ErrQuorumNotMet
+		}
+	}
+	if r.failureLimit > 0 && failed && !res.Skipped && !res.Cached {
+		if r.overloaded(span{end.Add(-res.Duration), end}) && !settled {
+			settled, err = true, ErrConcurrentFailures
+		}
+	}
+	var metrics RunnerMetrics
+	if r.completed != nil && !settled {
+		metrics = r.metrics()
+	}
+	r.m.Unlock()
+	for _, add := range r.aggregators {
+		add(res)
+	}
+	if r.slowTask != nil && res.Duration > r.slowAfter {
+		r.slowTask(t.index, res.Duration)
+	}
+	r.forget(t)
+	if failed {
+		r.streamError(TaskError{Index: t.index, Err: res.Err})
+	}
+	if r.completed != nil && !settled {
+		settled = r.completed(metrics)
+	}
+	if settled {
+		r.end(err)
+	}
+}
+
+// failure reports whether res counts as a failure.
+func (r *Runner) failure(res TaskResult) bool {
+	if res.Err == nil {
+		return false
+	}
+	return !r.softTaskTimeout || !errors.Is(res.Err, ErrTaskTimeout)
+}
+
+// cutOff stops the dispatch of new tasks, letting the workers exit once
+// their running task has finished, and makes the run end with err.
+func (r *Runner) cutOff(err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.cutoff == nil {
+		r.cutoff = err
+	}
+	r.cond.Broadcast()
+}
+
+// spend adds d, the time a task took, to the work spent. It is called by
+// the worker before it asks for its next task, so that the budget is
+// checked against every task that has finished so far.
+func (r *Runner) spend(d time.Duration) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.workSpent += d
+	if r.workBudget > 0 && r.workSpent > r.workBudget {
+		// let the waiting workers see the budget is used up
+		r.cond.Broadcast()
+	}
+}
+
+// finalErr returns the error of a run whose workers have all returned,
+// r.m must not be held.
+func (r *Runner) finalErr() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.workBudget > 0 && r.workSpent > r.workBudget {
+		return ErrWorkBudgetExceeded
+	}
+	if r.quorum > 0 && r.succeeded < r.quorum {
+		return ErrQuorumNotMet
+	}
+	// an interrupted run fails even if no task was left
+	if r.cutoff == ErrInterrupt || (r.cutoff != nil && r.finished < len(r.tasks)) {
+		return r.cutoff
+	}
+	return nil
+}
+
+// end records the outcome of the run the first time it is called and
+// stops the dispatch of new tasks, r.m must not be held.
+func (r *Runner) end(err error) {
+	r.endOnce.Do(func() {
+		// done is closed under the lock so that a worker about to wait
+		// for a task either sees it closed or gets the broadcast.
+		r.m.Lock()
+		close(r.done)
+		r.dispatching = false
+		r.outcome = outcomeOf(err)
+		r.endedWith = err
+		r.m.Unlock()
+		r.cond.Broadcast()
+		r.cancelRun()
+		r.completeMain <- err
+	})
+}
+
+// logPanic reports the panic p recovered from t, along with the stack of
+// the panicking goroutine, when there is no panic handler. Unlike the
+// lifecycle events it goes to the default slog logger without a logger.
+func (r *Runner) logPanic(t *task, p any, stack []byte) {
+	logger := r.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs := []slog.Attr{slog.Int("task", t.index), slog.Any("panic", p), slog.String("stack", string(stack))}
+	if r.name != "" {
+		attrs = append([]slog.Attr{slog.String("runner", r.name)}, attrs...)
+	}
+	logger.LogAttrs(context.Background(), slog.LevelError, "task panicked", attrs...)
+}
+
+// log emits a lifecycle event on the configured logger, if any.
+func (r *Runner) log(msg string, attrs ...slog.Attr) {
+	if r.logger == nil {
+		return
+	}
+	if r.name != "" {
+		attrs = append([]slog.Attr{slog.String("runner", r.name)}, attrs...)
 	}
-	return
+	r.logger.LogAttrs(context.Background(), slog.LevelInfo, msg, attrs...)
 }
@@ -0,0 +1,908 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkBudgetStopsNewTasks(t *testing.T) {
+	r := NewWithOptions(5*time.Second, 1, WithTotalWorkBudget(125*time.Millisecond))
+	var ran atomic.Int32
+	for i := 0; i < 10; i++ {
+		r.Add(func(int) {
+			ran.Add(1)
+			time.Sleep(50 * time.Millisecond)
+		})
+	}
+	if err := r.Start(); !errors.Is(err, ErrWorkBudgetExceeded) {
+		t.Fatalf("Start() = %v, want %v", err, ErrWorkBudgetExceeded)
+	}
+	// the budget is exceeded by the third task, no fourth one starts
+	if n := ran.Load(); n != 3 {
+		t.Fatalf("%d tasks ran, want 3", n)
+	}
+}
+
+// captureHandler is a slog.Handler recording the records it handles.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, rec slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, rec)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *captureHandler) WithGroup(string) slog.Handler { return h }
+
+// find returns the attributes of the records with message msg.
+func (h *captureHandler) find(msg string) []map[string]slog.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var found []map[string]slog.Value
+	for _, rec := range h.records {
+		if rec.Message != msg {
+			continue
+		}
+		attrs := make(map[string]slog.Value)
+		rec.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value
+			return true
+		})
+		found = append(found, attrs)
+	}
+	return found
+}
+
+func TestSlogLifecycleEvents(t *testing.T) {
+	h := &captureHandler{}
+	r := NewWithOptions(time.Second, 1, WithSlog(slog.New(h)))
+	r.Add(func(int) {})
+	r.AddFallible(func(int) error { return errors.New("boom") })
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(h.find("run started")) != 1 || len(h.find("run completed")) != 1 {
+		t.Fatal("run start and end not logged")
+	}
+	finished := h.find("task finished")
+	if len(finished) != 2 {
+		t.Fatalf("%d task finished records, want 2", len(finished))
+	}
+	for i, attrs := range finished {
+		for _, key := range []string{"task", "worker", "outcome", "duration"} {
+			if _, ok := attrs[key]; !ok {
+				t.Fatalf("record %d lacks attribute %q", i, key)
+			}
+		}
+		if got := attrs["task"].Int64(); got != int64(i) {
+			t.Fatalf("record %d has task %d", i, got)
+		}
+	}
+	if got := finished[1]["outcome"].String(); got != "failed" {
+		t.Fatalf("outcome of the failing task = %q, want failed", got)
+	}
+}
+
+func TestSlogSilentByDefault(t *testing.T) {
+	h := &captureHandler{}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(prev)
+	r := New(time.Second, 1)
+	r.Add(func(int) {})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(h.records) != 0 {
+		t.Fatalf("%d records logged without a logger", len(h.records))
+	}
+}
+
+// BenchmarkStart_10kTasks measures registering and running 10k no-op tasks
+// on 8 workers. Run it with -benchmem to follow the allocations of the
+// dispatch path.
+func BenchmarkStart_10kTasks(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := New(time.Minute, 8)
+		for j := 0; j < 10000; j++ {
+			r.Add(func(int) {})
+		}
+		if err := r.Start(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChannelBuffers(b *testing.B) {
+	for _, bc := range []struct {
+		name     string
+		complete int
+	}{
+		{"unbuffered", 0},
+		{"buffered", 256},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := NewWithOptions(time.Minute, 8, WithChannelBuffers(bc.complete, 1))
+				for j := 0; j < 10000; j++ {
+					r.Add(func(int) {})
+				}
+				if err := r.Start(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestQuorumReturnsEarly(t *testing.T) {
+	r := NewWithOptions(5*time.Second, 5, WithQuorum(3))
+	for i := 0; i < 3; i++ {
+		r.Add(func(int) {})
+	}
+	done := r.Done()
+	for i := 0; i < 2; i++ {
+		r.Add(func(int) {
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+			}
+		})
+	}
+	start := time.Now()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Start() took %v, want an early return", d)
+	}
+	if n := r.Metrics().Succeeded; n < 3 {
+		t.Fatalf("%d tasks succeeded, want at least 3", n)
+	}
+}
+
+func TestQuorumNotMet(t *testing.T) {
+	r := NewWithOptions(5*time.Second, 2, WithQuorum(3))
+	for i := 0; i < 5; i++ {
+		i := i
+		r.AddFallible(func(int) error {
+			if i < 3 {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+	if err := r.Start(); !errors.Is(err, ErrQuorumNotMet) {
+		t.Fatalf("Start() = %v, want %v", err, ErrQuorumNotMet)
+	}
+}
+
+func TestPanicHandlerReceivesPanic(t *testing.T) {
+	var (
+		index     int
+		recovered any
+		stack     []byte
+	)
+	r := NewWithOptions(time.Second, 1, WithPanicHandler(func(i int, p any, s []byte) {
+		index, recovered, stack = i, p, s
+	}))
+	r.Add(func(int) {})
+	r.Add(func(int) { panic("boom") })
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if index != 1 || recovered != "boom" {
+		t.Fatalf("handler got task %d and %v, want task 1 and boom", index, recovered)
+	}
+	if !strings.Contains(string(stack), "TestPanicHandlerReceivesPanic") {
+		t.Fatalf("stack does not show the panicking task:\n%s", stack)
+	}
+	if err := r.Results()[1].Err; !errors.Is(err, ErrTaskPanicked) {
+		t.Fatalf("task error = %v, want %v", err, ErrTaskPanicked)
+	}
+}
+
+func TestPanicRecoveredByDefault(t *testing.T) {
+	h := &captureHandler{}
+	r := NewWithOptions(time.Second, 1, WithSlog(slog.New(h)))
+	r.Add(func(int) { panic("boom") })
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if err := r.Results()[0].Err; !errors.Is(err, ErrTaskPanicked) {
+		t.Fatalf("task error = %v, want %v", err, ErrTaskPanicked)
+	}
+	logged := h.find("task panicked")
+	if len(logged) != 1 || logged[0]["panic"].Any() != "boom" {
+		t.Fatalf("panic not logged: %v", logged)
+	}
+}
+
+// blocker returns a task blocking until the run ends.
+func blocker(r *Runner) func(int) {
+	done := r.Done()
+	return func(int) { <-done }
+}
+
+func TestDeadlineBeatsTimeout(t *testing.T) {
+	r := NewWithOptions(time.Hour, 1,
+		WithTimeout(time.Second),
+		WithDeadline(time.Now().Add(20*time.Millisecond)),
+	)
+	r.Add(blocker(r))
+	if err := r.Start(); err != ErrDeadline {
+		t.Fatalf("Start() = %v, want %v", err, ErrDeadline)
+	}
+}
+
+func TestTimeoutBeatsDeadline(t *testing.T) {
+	r := NewWithOptions(time.Hour, 1,
+		WithTimeout(20*time.Millisecond),
+		WithDeadline(time.Now().Add(time.Second)),
+	)
+	r.Add(blocker(r))
+	if err := r.Start(); err != ErrTimeout {
+		t.Fatalf("Start() = %v, want %v", err, ErrTimeout)
+	}
+	if got := r.Config().Timeout; got != 20*time.Millisecond {
+		t.Fatalf("Config().Timeout = %v, want the one set with WithTimeout", got)
+	}
+}
+
+func TestQueueSampleDrains(t *testing.T) {
+	r := New(5*time.Second, 1)
+	var mu sync.Mutex
+	var depths []int
+	r.OnQueueSample(5*time.Millisecond, func(depth int) {
+		mu.Lock()
+		depths = append(depths, depth)
+		mu.Unlock()
+	})
+	for i := 0; i < 50; i++ {
+		r.Add(func(int) { time.Sleep(2 * time.Millisecond) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(depths) < 2 {
+		t.Fatalf("%d samples, want several", len(depths))
+	}
+	for i := 1; i < len(depths); i++ {
+		if depths[i] > depths[i-1] {
+			t.Fatalf("depth grew from %d to %d while draining: %v", depths[i-1], depths[i], depths)
+		}
+	}
+	if depths[0] <= depths[len(depths)-1] {
+		t.Fatalf("depth did not decrease: %v", depths)
+	}
+}
+
+func TestAddDuringCompletions(t *testing.T) {
+	for iter := 0; iter < 50; iter++ {
+		r := New(5*time.Second, 4)
+		var added, ran atomic.Int32
+		var spawn func(depth int) func(int)
+		spawn = func(depth int) func(int) {
+			return func(int) {
+				ran.Add(1)
+				if depth == 0 {
+					return
+				}
+				// add children while sibling tasks are completing
+				for i := 0; i < 3; i++ {
+					added.Add(1)
+					r.Add(spawn(depth - 1))
+				}
+			}
+		}
+		for i := 0; i < 4; i++ {
+			added.Add(1)
+			r.Add(spawn(3))
+		}
+		if err := r.Start(); err != nil {
+			t.Fatalf("Start() = %v, want nil", err)
+		}
+		if ran.Load() != added.Load() {
+			t.Fatalf("%d of %d tasks ran before the run ended", ran.Load(), added.Load())
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var ran atomic.Int32
+	task := func(int) { ran.Add(1) }
+	if err := Run(time.Second, 2, task, task); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if ran.Load() != 2 {
+		t.Fatalf("%d tasks ran, want 2", ran.Load())
+	}
+	slow := func(int) { time.Sleep(time.Second) }
+	if err := Run(20*time.Millisecond, 1, slow); err != ErrTimeout {
+		t.Fatalf("Run() = %v, want %v", err, ErrTimeout)
+	}
+}
+
+func TestRestartMidRun(t *testing.T) {
+	r := New(5*time.Second, 1)
+	var oldRan, newRan atomic.Int32
+	started := make(chan struct{})
+	done := r.Done()
+	r.Add(func(int) {
+		oldRan.Add(1)
+		close(started)
+		<-done
+	})
+	for i := 0; i < 5; i++ {
+		r.Add(func(int) { oldRan.Add(1) })
+	}
+	first := make(chan error)
+	go func() { first <- r.Start() }()
+	<-started
+	var tasks []func(int)
+	for i := 0; i < 3; i++ {
+		tasks = append(tasks, func(int) { newRan.Add(1) })
+	}
+	if err := r.Restart(tasks...); err != nil {
+		t.Fatalf("Restart() = %v, want nil", err)
+	}
+	if err := <-first; err != ErrStopped {
+		t.Fatalf("first Start() = %v, want %v", err, ErrStopped)
+	}
+	if oldRan.Load() != 1 {
+		t.Fatalf("%d tasks of the old batch ran, want 1", oldRan.Load())
+	}
+	if newRan.Load() != 3 {
+		t.Fatalf("%d tasks of the new batch ran, want 3", newRan.Load())
+	}
+	if n := len(r.Results()); n != 3 {
+		t.Fatalf("%d results after Restart, want 3", n)
+	}
+}
+
+func TestRestartDropsStaleInterrupt(t *testing.T) {
+	r := New(5*time.Second, 1)
+	r.Add(func(int) {})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	// a signal arriving after the run ended
+	r.interrupt <- os.Interrupt
+	if err := r.Restart(func(int) {}); err != nil {
+		t.Fatalf("Restart() = %v, want nil", err)
+	}
+}
+
+func TestTimeoutDrain(t *testing.T) {
+	r := NewWithOptions(20*time.Millisecond, 1, WithTimeoutDrain())
+	var finished, second atomic.Bool
+	r.Add(func(int) {
+		time.Sleep(60 * time.Millisecond)
+		finished.Store(true)
+	})
+	r.Add(func(int) { second.Store(true) })
+	if err := r.Start(); err != ErrTimeout {
+		t.Fatalf("Start() = %v, want %v", err, ErrTimeout)
+	}
+	if !finished.Load() {
+		t.Fatal("Start returned before the in-flight task finished")
+	}
+	if second.Load() {
+		t.Fatal("a task started after the timeout")
+	}
+}
+
+func TestStartWith(t *testing.T) {
+	r := New(time.Second, 1)
+	var order []int
+	r.Add(func(int) { order = append(order, 0) })
+	err := r.StartWith(
+		func(int) { order = append(order, 1) },
+		func(int) { order = append(order, 2) },
+	)
+	if err != nil {
+		t.Fatalf("StartWith() = %v, want nil", err)
+	}
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Fatalf("tasks ran in order %v, want [0 1 2]", order)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	r := New(5*time.Second, 1)
+	started, release := make(chan struct{}), make(chan struct{})
+	exited := make(chan struct{})
+	r.OnWorkerExit(func(int) { close(exited) })
+	var queuedRan atomic.Bool
+	r.Add(func(int) {
+		close(started)
+		<-release
+	})
+	for i := 0; i < 5; i++ {
+		r.Add(func(int) { queuedRan.Store(true) })
+	}
+	go func() {
+		<-started
+		r.Abort()
+	}()
+	if err := r.Start(); err != ErrAborted {
+		t.Fatalf("Start() = %v, want %v", err, ErrAborted)
+	}
+	// Start returned while the task was still running, let it finish
+	// and the worker get to the queue
+	close(release)
+	<-exited
+	if queuedRan.Load() {
+		t.Fatal("a queued task ran after Abort")
+	}
+}
+
+func TestWorkerLifecycleHooks(t *testing.T) {
+	r := New(time.Second, 4)
+	var mu sync.Mutex
+	starts, exits := make(map[int]int), make(map[int]int)
+	r.OnWorkerStart(func(id int) {
+		mu.Lock()
+		starts[id]++
+		mu.Unlock()
+	})
+	r.OnWorkerExit(func(id int) {
+		mu.Lock()
+		exits[id]++
+		mu.Unlock()
+	})
+	for i := 0; i < 10; i++ {
+		r.Add(func(int) {})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(starts) != 4 || len(exits) != 4 {
+		t.Fatalf("%d workers started and %d exited, want 4 and 4", len(starts), len(exits))
+	}
+	for id := 0; id < 4; id++ {
+		if starts[id] != 1 || exits[id] != 1 {
+			t.Fatalf("worker %d started %d and exited %d times, want once each", id, starts[id], exits[id])
+		}
+	}
+}
+
+func TestIdleTimeoutRespawnsWorkers(t *testing.T) {
+	r := NewWithOptions(time.Second, 3, WithIdleTimeout(20*time.Millisecond))
+	var starts, exits atomic.Int32
+	r.OnWorkerStart(func(int) { starts.Add(1) })
+	r.OnWorkerExit(func(int) { exits.Add(1) })
+	release := make(chan struct{})
+	r.Add(func(int) { <-release })
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	deadline := time.Now().Add(time.Second)
+	for exits.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d workers exited while idle, want 2", exits.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var burst sync.WaitGroup
+	burst.Add(2)
+	for i := 0; i < 2; i++ {
+		r.Add(func(int) {
+			burst.Done()
+			burst.Wait()
+		})
+	}
+	burst.Wait()
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := starts.Load(); got != 5 {
+		t.Fatalf("workers started %d times, want 5", got)
+	}
+	if got := exits.Load(); got != 5 {
+		t.Fatalf("workers exited %d times, want 5", got)
+	}
+}
+
+func TestYieldBetweenTasks(t *testing.T) {
+	r := NewWithOptions(time.Second, 2, WithYieldBetweenTasks())
+	var ran atomic.Int32
+	for i := 0; i < 100; i++ {
+		r.Add(func(int) { ran.Add(1) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := ran.Load(); got != 100 {
+		t.Fatalf("%d tasks ran, want 100", got)
+	}
+	if !r.Config().YieldBetweenTasks {
+		t.Fatal("Config().YieldBetweenTasks = false, want true")
+	}
+}
+
+func TestPrewarm(t *testing.T) {
+	r := New(time.Second, 3)
+	var starts atomic.Int32
+	r.OnWorkerStart(func(int) { starts.Add(1) })
+	r.Prewarm()
+	deadline := time.Now().Add(time.Second)
+	for starts.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d workers alive before Start, want 3", starts.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var ran atomic.Int32
+	for i := 0; i < 10; i++ {
+		r.Add(func(int) { ran.Add(1) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := ran.Load(); got != 10 {
+		t.Fatalf("%d tasks ran, want 10", got)
+	}
+	if got := starts.Load(); got != 3 {
+		t.Fatalf("workers started %d times, want 3", got)
+	}
+}
+
+func TestQueueDrainedTopsUp(t *testing.T) {
+	r := New(time.Second, 2)
+	var ran, drained atomic.Int32
+	task := func(int) { ran.Add(1) }
+	r.OnQueueDrained(func() {
+		if drained.Add(1) == 1 {
+			r.Add(task, task, task)
+		}
+	})
+	r.Add(task, task, task)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := ran.Load(); got != 6 {
+		t.Fatalf("%d tasks ran, want the 3 added first and the 3 topped up", got)
+	}
+	if got := drained.Load(); got != 2 {
+		t.Fatalf("OnQueueDrained called %d times, want 2", got)
+	}
+}
+
+// goid returns the id of the calling goroutine.
+func goid() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	return strings.Fields(string(buf))[1]
+}
+
+func TestSynchronous(t *testing.T) {
+	r := NewWithOptions(time.Second, 4, WithSynchronous())
+	caller := goid()
+	var order []int
+	for i := 0; i < 10; i++ {
+		i := i
+		r.Add(func(int) {
+			if g := goid(); g != caller {
+				t.Errorf("task %d ran on goroutine %s, want %s", i, g, caller)
+			}
+			order = append(order, i)
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("tasks ran in order %v, want registration order", order)
+		}
+	}
+	if len(order) != 10 {
+		t.Fatalf("%d tasks ran, want 10", len(order))
+	}
+}
+
+func TestCompletionPredicate(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithCompletionPredicate(func(m RunnerMetrics) bool {
+		return m.Succeeded >= 2 && m.Failed >= 1
+	}))
+	var ran atomic.Int32
+	for i := 0; i < 10; i++ {
+		fail := i == 1
+		r.AddFallible(func(int) error {
+			ran.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			if fail {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := ran.Load(); got < 3 || got > 4 {
+		t.Fatalf("%d tasks ran, want the run to end after the third", got)
+	}
+	if m := r.Metrics(); m.Succeeded < 2 || m.Failed != 1 {
+		t.Fatalf("Metrics() = %+v, want 2 successes and 1 failure at least", m)
+	}
+}
+
+func TestTaskFilterSubstitutes(t *testing.T) {
+	var original, stub atomic.Int32
+	r := NewWithOptions(time.Second, 2, WithTaskFilter(func(index int, task func(int)) func(int) {
+		switch index {
+		case 1:
+			return func(int) { stub.Add(1) }
+		case 2:
+			return nil
+		}
+		return task
+	}))
+	for i := 0; i < 3; i++ {
+		r.Add(func(int) { original.Add(1) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := original.Load(); got != 1 {
+		t.Fatalf("%d original tasks ran, want 1", got)
+	}
+	if got := stub.Load(); got != 1 {
+		t.Fatalf("the stub ran %d times, want 1", got)
+	}
+	if res := r.Results()[2]; !res.Skipped {
+		t.Fatalf("Results()[2] = %+v, want it skipped", res)
+	}
+}
+
+func TestWorkerInit(t *testing.T) {
+	var inits atomic.Int32
+	r := NewWithOptions(time.Second, 3, WithWorkerInit(func(int) any {
+		inits.Add(1)
+		return new(int)
+	}))
+	for i := 0; i < 30; i++ {
+		r.AddWithWorkerValue(func(id int, v any) {
+			*v.(*int)++
+			time.Sleep(time.Millisecond)
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := inits.Load(); got != 3 {
+		t.Fatalf("init ran %d times, want once per worker", got)
+	}
+	perWorker := make(map[int]int)
+	for _, res := range r.Results() {
+		perWorker[res.Worker]++
+	}
+	seen := make(map[*int]bool)
+	for id := 0; id < 3; id++ {
+		counter := r.WorkerValue(id).(*int)
+		if seen[counter] {
+			t.Fatalf("worker %d shares its value with another worker", id)
+		}
+		seen[counter] = true
+		if *counter != perWorker[id] {
+			t.Fatalf("worker %d counted %d tasks, ran %d", id, *counter, perWorker[id])
+		}
+	}
+}
+
+func TestDoneClosesOnCompletion(t *testing.T) {
+	r := New(time.Second, 1)
+	done := r.Done()
+	select {
+	case <-done:
+		t.Fatal("Done() closed before Start")
+	default:
+	}
+	r.Add(func(int) { time.Sleep(10 * time.Millisecond) })
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Done() did not close once the run completed")
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+}
+
+func TestOnSlowTask(t *testing.T) {
+	r := New(time.Second, 2)
+	var mu sync.Mutex
+	var slow []int
+	r.OnSlowTask(30*time.Millisecond, func(index int, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		slow = append(slow, index)
+		if d < 30*time.Millisecond {
+			t.Errorf("task %d reported slow after %v", index, d)
+		}
+	})
+	for i := 0; i < 5; i++ {
+		d := time.Millisecond
+		if i == 3 {
+			d = 50 * time.Millisecond
+		}
+		r.Add(func(int) { time.Sleep(d) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(slow) != 1 || slow[0] != 3 {
+		t.Fatalf("OnSlowTask reported tasks %v, want only task 3", slow)
+	}
+}
+
+func TestGoroutineCount(t *testing.T) {
+	r := New(time.Second, 4)
+	if got := r.GoroutineCount(); got != 0 {
+		t.Fatalf("GoroutineCount() = %d before the run, want 0", got)
+	}
+	var running sync.WaitGroup
+	running.Add(4)
+	counts := make(chan int, 1)
+	for i := 0; i < 4; i++ {
+		i := i
+		r.Add(func(int) {
+			running.Done()
+			running.Wait()
+			if i == 0 {
+				counts <- r.GoroutineCount()
+			}
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	// the workers and the goroutine collecting their results
+	if got := <-counts; got != 4+1 {
+		t.Fatalf("GoroutineCount() = %d mid-run, want 5", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.GoroutineCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("GoroutineCount() = %d after the run, want 0", r.GoroutineCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRampUp(t *testing.T) {
+	const step = 20 * time.Millisecond
+	r := NewWithOptions(time.Second, 3, WithRampUp(step))
+	var mu sync.Mutex
+	started := make(map[int]time.Time)
+	r.OnWorkerStart(func(id int) {
+		mu.Lock()
+		defer mu.Unlock()
+		started[id] = time.Now()
+	})
+	for i := 0; i < 6; i++ {
+		r.Add(func(int) { time.Sleep(30 * time.Millisecond) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(started) != 3 {
+		t.Fatalf("%d workers started, want 3", len(started))
+	}
+	for id := 1; id < 3; id++ {
+		gap := started[id].Sub(started[id-1])
+		if gap < step-5*time.Millisecond || gap > 3*step {
+			t.Fatalf("worker %d started %v after worker %d, want about %v", id, gap, id-1, step)
+		}
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	r := New(50*time.Millisecond, 1)
+	started := make(chan struct{})
+	r.Add(func(int) {
+		close(started)
+		<-r.Done()
+	})
+	if _, ok := r.WaitTimeout(time.Millisecond); ok {
+		t.Fatal("WaitTimeout() = true before Start, want false")
+	}
+	go r.Start()
+	<-started
+	if _, ok := r.WaitTimeout(5 * time.Millisecond); ok {
+		t.Fatal("WaitTimeout() = true while the run is in progress, want false")
+	}
+	err, ok := r.WaitTimeout(time.Second)
+	if !ok || err != ErrTimeout {
+		t.Fatalf("WaitTimeout() = %v, %v, want %v, true", err, ok, ErrTimeout)
+	}
+}
+
+func TestResetDuringRun(t *testing.T) {
+	r := New(time.Second, 1)
+	started, release := make(chan struct{}), make(chan struct{})
+	r.Add(func(int) {
+		close(started)
+		<-release
+	})
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	<-started
+	if err := r.Reset(); err != ErrRunInProgress {
+		t.Fatalf("Reset() = %v mid-run, want %v", err, ErrRunInProgress)
+	}
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatalf("Reset() = %v after the run, want nil", err)
+	}
+}
+
+func TestWithNameTagsLogs(t *testing.T) {
+	h := &captureHandler{}
+	logger := slog.New(h)
+	var wg sync.WaitGroup
+	for _, name := range []string{"ingest", "export"} {
+		r := NewWithOptions(time.Second, 2, WithSlog(logger), WithName(name))
+		for i := 0; i < 3; i++ {
+			r.Add(func(int) {})
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Start()
+		}()
+	}
+	wg.Wait()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	perRunner := make(map[string]int)
+	for _, rec := range h.records {
+		name := ""
+		rec.Attrs(func(a slog.Attr) bool {
+			if a.Key == "runner" {
+				name = a.Value.String()
+			}
+			return true
+		})
+		if name == "" {
+			t.Fatalf("record %q carries no runner name", rec.Message)
+		}
+		perRunner[name]++
+	}
+	if len(perRunner) != 2 || perRunner["ingest"] != perRunner["export"] {
+		t.Fatalf("records per runner = %v, want as many for each of the two runners", perRunner)
+	}
+}
@@ -0,0 +1,46 @@
+package runner
+
+import "time"
+
+// Rusage is the CPU time the process used during a run, see
+// ResourceUsage.
+type Rusage struct {
+	// User is the CPU time spent in user mode.
+	User time.Duration
+
+	// System is the CPU time spent in the kernel on behalf of the
+	// process.
+	System time.Duration
+}
+
+// sub returns the usage from u to v.
+func (v Rusage) sub(u Rusage) Rusage {
+	return Rusage{User: v.User - u.User, System: v.System - u.System}
+}
+
+// ResourceUsage returns the CPU time the process used from the start of the
+// last run until Start returned, as reported by getrusage. The figures
+// cover the whole process, so they include the work of anything else
+// running alongside the runner. It returns zero before the first run has
+// ended, and an error wrapping errors.ErrUnsupported on platforms without
+// getrusage.
+func (r *Runner) ResourceUsage() (Rusage, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.usage, r.usageErr
+}
+
+// measureUsage records the usage of the run that began with usage start,
+// or the error sampling it, once the run has ended.
+func (r *Runner) measureUsage(start Rusage, err error) {
+	var end Rusage
+	if err == nil {
+		end, err = processUsage()
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.usage, r.usageErr = end.sub(start), err
+	if err != nil {
+		r.usage = Rusage{}
+	}
+}
@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var spinSink atomic.Uint64
+
+func TestResourceUsage(t *testing.T) {
+	r := New(5*time.Second, 2)
+	for i := 0; i < 2; i++ {
+		r.Add(func(int) {
+			// spin until the process has used 50ms of CPU, however
+			// busy the machine is
+			start, _ := processUsage()
+			var x uint64
+			for {
+				for j := 0; j < 1000; j++ {
+					x = x*31 + uint64(j)
+				}
+				now, _ := processUsage()
+				if u := now.sub(start); u.User+u.System >= 50*time.Millisecond {
+					break
+				}
+			}
+			spinSink.Add(x)
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	usage, err := r.ResourceUsage()
+	if err != nil {
+		t.Fatalf("ResourceUsage() = %v, want nil", err)
+	}
+	if usage.User+usage.System < 50*time.Millisecond {
+		t.Fatalf("ResourceUsage() = %+v after 50ms of CPU-bound work, want at least 50ms", usage)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+)
+
+// processUsage reports that the CPU time of the process cannot be sampled
+// on this platform.
+func processUsage() (Rusage, error) {
+	return Rusage{}, fmt.Errorf("resource usage: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,70 @@
+package runner
+
+// sequence is the state of a group of tasks added with AddSequentialGroup.
+type sequence struct {
+	// waiting holds the tasks behind the one in flight, in order.
+	waiting []*task
+
+	// busy is set while a task of the group is queued or running.
+	busy bool
+}
+
+// AddSequentialGroup attaches tasks to the named sequential group. The
+// tasks of a group run one at a time, in the order they were added, each
+// starting once the previous one has finished, while the tasks of other
+// groups and the other tasks run alongside them. Adding to a group that
+// already has tasks appends to its sequence.
+func (r *Runner) AddSequentialGroup(name string, tasks ...func(int)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.sequences == nil {
+		r.sequences = make(map[string]*sequence)
+	}
+	s := r.sequences[name]
+	if s == nil {
+		s = &sequence{}
+		r.sequences[name] = s
+	}
+	r.tasks = grow(r.tasks, len(tasks))
+	for _, fn := range tasks {
+		t := r.alloc(Normal, fn)
+		t.more().sequence = name
+		if s.busy {
+			s.waiting = append(s.waiting, t)
+			r.sequenced++
+			continue
+		}
+		s.busy = true
+		r.queue.push(t)
+		r.wakeFor(t)
+	}
+}
+
+// advanceSequence queues the task following t in its sequential group
+// once t has finished, r.m must be held.
+func (r *Runner) advanceSequence(t *task) {
+	s := r.sequences[t.ext().sequence]
+	if s == nil {
+		return
+	}
+	if len(s.waiting) == 0 {
+		s.busy = false
+		return
+	}
+	next := s.waiting[0]
+	s.waiting = s.waiting[1:]
+	r.sequenced--
+	r.queue.push(next)
+	r.wakeFor(next)
+}
+
+// unchain returns the tasks waiting in the sequential groups and forgets
+// the groups, r.m must be held.
+func (r *Runner) unchain() []*task {
+	var tasks []*task
+	for _, s := range r.sequences {
+		tasks = append(tasks, s.waiting...)
+	}
+	r.sequences, r.sequenced = nil, 0
+	return tasks
+}
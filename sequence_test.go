@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSequentialGroups(t *testing.T) {
+	r := New(time.Second, 4)
+	var mu sync.Mutex
+	active := make(map[string]int)
+	order := make(map[string][]int)
+	running, overlap := 0, 0
+	for _, name := range []string{"a", "b"} {
+		name := name
+		for i := 0; i < 4; i++ {
+			i := i
+			r.AddSequentialGroup(name, func(int) {
+				mu.Lock()
+				active[name]++
+				if active[name] > 1 {
+					t.Errorf("%d tasks of group %s ran at once", active[name], name)
+				}
+				order[name] = append(order[name], i)
+				running++
+				overlap = max(overlap, running)
+				mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				mu.Lock()
+				active[name]--
+				running--
+				mu.Unlock()
+			})
+		}
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		got := order[name]
+		if len(got) != 4 || got[0] != 0 || got[1] != 1 || got[2] != 2 || got[3] != 3 {
+			t.Fatalf("group %s ran in order %v, want [0 1 2 3]", name, got)
+		}
+	}
+	if overlap < 2 {
+		t.Fatal("the two groups never ran at the same time")
+	}
+}
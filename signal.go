@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"os"
+	"os/signal"
+)
+
+// Notifier relays operating system signals to channels. It is satisfied by
+// the os/signal package, which the runner uses by default, and can be
+// replaced with WithNotifier to drive signals in tests.
+type Notifier interface {
+	// Notify relays the signals sigs to c.
+	Notify(c chan<- os.Signal, sigs ...os.Signal)
+
+	// Stop stops relaying signals to c.
+	Stop(c chan<- os.Signal)
+}
+
+// osNotifier is the Notifier backed by the os/signal package.
+type osNotifier struct{}
+
+// Notify implements Notifier.
+func (osNotifier) Notify(c chan<- os.Signal, sigs ...os.Signal) {
+	signal.Notify(c, sigs...)
+}
+
+// Stop implements Notifier.
+func (osNotifier) Stop(c chan<- os.Signal) {
+	signal.Stop(c)
+}
+
+// OnSignal registers fn to be called whenever sig is received while the run
+// is in progress. Unlike an interrupt, such a signal does not terminate the
+// run, which makes it suitable for things like reloading configuration on
+// SIGHUP. Several handlers may be registered for the same signal. OnSignal
+// must be called before Start.
+func (r *Runner) OnSignal(sig os.Signal, fn func()) {
+	if r.signalHandlers == nil {
+		r.signalHandlers = make(map[os.Signal][]func())
+	}
+	r.signalHandlers[sig] = append(r.signalHandlers[sig], fn)
+}
+
+// notifySignals starts relaying the signals registered with OnSignal.
+func (r *Runner) notifySignals() chan os.Signal {
+	sigs := make([]os.Signal, 0, len(r.signalHandlers))
+	for sig := range r.signalHandlers {
+		sigs = append(sigs, sig)
+	}
+	c := make(chan os.Signal, 1)
+	r.notifier.Notify(c, sigs...)
+	return c
+}
+
+// handleSignals dispatches the signals received on c to their handlers
+// until done is closed.
+func (r *Runner) handleSignals(c chan os.Signal, done <-chan struct{}) {
+	defer r.notifier.Stop(c)
+	for {
+		select {
+		case sig := <-c:
+			for _, fn := range r.signalHandlers[sig] {
+				fn()
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package runner
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testSignal is an os.Signal that no process ever receives, delivered by
+// the tests themselves.
+type testSignal string
+
+func (s testSignal) String() string { return string(s) }
+func (s testSignal) Signal()        {}
+
+// fakeNotifier is a Notifier whose signals are delivered by the test.
+type fakeNotifier struct {
+	mu       sync.Mutex
+	channels map[os.Signal][]chan<- os.Signal
+	calls    int
+}
+
+func (n *fakeNotifier) Notify(c chan<- os.Signal, sigs ...os.Signal) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	if n.channels == nil {
+		n.channels = make(map[os.Signal][]chan<- os.Signal)
+	}
+	for _, sig := range sigs {
+		n.channels[sig] = append(n.channels[sig], c)
+	}
+}
+
+func (n *fakeNotifier) Stop(c chan<- os.Signal) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	for sig, cs := range n.channels {
+		for i, other := range cs {
+			if other == c {
+				n.channels[sig] = append(cs[:i:i], cs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// send delivers sig to the channels registered for it, once they are.
+func (n *fakeNotifier) send(t *testing.T, sig os.Signal) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		n.mu.Lock()
+		cs := append([]chan<- os.Signal(nil), n.channels[sig]...)
+		n.mu.Unlock()
+		if len(cs) > 0 {
+			for _, c := range cs {
+				c <- sig
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("nobody listens to %v", sig)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// registered reports whether a channel is registered for sig.
+func (n *fakeNotifier) registered(sig os.Signal) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.channels[sig]) > 0
+}
+
+func TestOnSignalDoesNotStopRun(t *testing.T) {
+	n := &fakeNotifier{}
+	r := NewWithOptions(time.Second, 1, WithNotifier(n))
+	reloaded := make(chan struct{})
+	hangup := testSignal("hangup")
+	r.OnSignal(hangup, func() { close(reloaded) })
+	started := make(chan struct{})
+	r.Add(func(int) {
+		close(started)
+		<-reloaded
+	})
+	go func() {
+		<-started
+		n.send(t, hangup)
+	}()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if r.Outcome() != Completed {
+		t.Fatalf("Outcome() = %v, want %v", r.Outcome(), Completed)
+	}
+	// the handlers stop listening shortly after the run ends
+	deadline := time.Now().Add(time.Second)
+	for n.registered(hangup) {
+		if time.Now().After(deadline) {
+			t.Fatal("hangup still relayed after the run")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitPaused waits for the dispatch of r to be paused or resumed.
+func waitPaused(t *testing.T, r *Runner, paused bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.m.Lock()
+		got := r.paused
+		r.m.Unlock()
+		if got == paused {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("paused = %v, want %v", got, paused)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPauseSignalToggles(t *testing.T) {
+	n := &fakeNotifier{}
+	pause := testSignal("pause")
+	r := NewWithOptions(5*time.Second, 1, WithNotifier(n), WithPauseSignal(pause))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var second atomic.Bool
+	r.Add(func(int) {
+		close(started)
+		<-release
+	})
+	r.Add(func(int) { second.Store(true) })
+	done := make(chan error)
+	go func() { done <- r.Start() }()
+	<-started
+	n.send(t, pause)
+	waitPaused(t, r, true)
+	close(release)
+	time.Sleep(30 * time.Millisecond)
+	if second.Load() {
+		t.Fatal("a task started while paused")
+	}
+	n.send(t, pause)
+	if err := <-done; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if !second.Load() {
+		t.Fatal("the task held back by the pause never ran")
+	}
+}
+
+func TestReceivedSignal(t *testing.T) {
+	for _, sig := range []os.Signal{os.Interrupt, syscall.SIGTERM} {
+		n := &fakeNotifier{}
+		r := NewWithOptions(time.Second, 1, WithNotifier(n))
+		if got := r.ReceivedSignal(); got != nil {
+			t.Fatalf("ReceivedSignal() = %v before the run, want nil", got)
+		}
+		r.Add(blocker(r))
+		go n.send(t, sig)
+		if err := r.Start(); err != ErrInterrupt {
+			t.Fatalf("Start() = %v, want %v", err, ErrInterrupt)
+		}
+		if got := r.ReceivedSignal(); got != sig {
+			t.Fatalf("ReceivedSignal() = %v, want %v", got, sig)
+		}
+	}
+}
+
+func TestNotifierDrivesInterrupt(t *testing.T) {
+	n := &fakeNotifier{}
+	r := NewWithOptions(time.Second, 1, WithNotifier(n))
+	r.Add(blocker(r))
+	ran := false
+	r.Add(func(int) { ran = true })
+	go n.send(t, syscall.SIGTERM)
+	if err := r.Start(); err != ErrInterrupt {
+		t.Fatalf("Start() = %v, want %v", err, ErrInterrupt)
+	}
+	if ran {
+		t.Fatal("a pending task ran after the interrupt")
+	}
+	if n.registered(os.Interrupt) || n.registered(syscall.SIGTERM) {
+		t.Fatal("the interrupt channel is still registered after Start returned")
+	}
+}
+
+func TestDoubleInterruptForce(t *testing.T) {
+	for _, interrupts := range []int{1, 2} {
+		interrupts := interrupts
+		n := &fakeNotifier{}
+		r := NewWithOptions(5*time.Second, 1, WithNotifier(n), WithDoubleInterruptForce(time.Second))
+		started, finished := make(chan struct{}), make(chan struct{})
+		r.Add(func(int) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			close(finished)
+		})
+		ran := false
+		r.Add(func(int) { ran = true })
+		go func() {
+			<-started
+			for i := 0; i < interrupts; i++ {
+				n.send(t, os.Interrupt)
+			}
+		}()
+		err := r.Start()
+		select {
+		case <-finished:
+			if interrupts == 2 {
+				t.Fatal("Start() waited for the running task after a second interrupt")
+			}
+		default:
+			if interrupts == 1 {
+				t.Fatal("Start() returned before the running task drained")
+			}
+		}
+		if want := map[int]error{1: ErrInterrupt, 2: ErrAborted}[interrupts]; err != want {
+			t.Fatalf("Start() = %v after %d interrupts, want %v", err, interrupts, want)
+		}
+		if ran {
+			t.Fatal("a pending task started after the interrupt")
+		}
+		<-finished
+	}
+}
+
+func TestWithoutSignalHandling(t *testing.T) {
+	n := &fakeNotifier{}
+	r := NewWithOptions(time.Second, 1, WithNotifier(n), WithoutSignalHandling())
+	r.OnSignal(testSignal("hangup"), func() {})
+	r.Add(func(int) { r.Stop() })
+	r.Add(func(int) {})
+	if err := r.Start(); err != ErrStopped {
+		t.Fatalf("Start() = %v, want %v", err, ErrStopped)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.calls != 0 {
+		t.Fatalf("the notifier was called %d times, want none", n.calls)
+	}
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"errors"
+	"time"
+)
+
+// defaultTimeSlice is the quantum of the sliced tasks when WithTimeSlice is
+// not used.
+const defaultTimeSlice = 10 * time.Millisecond
+
+// errYielded is returned by a sliced task that gave its worker up before
+// finishing, the worker then queues it again.
+var errYielded = errors.New("task yielded")
+
+// slice is the state of a task added with AddSliced. The task runs on its
+// own goroutine, which hands control back and forth with the worker
+// running the current slice.
+type slice struct {
+	fn func(id int, yield func() bool)
+
+	// resume starts the next slice, yielded reports the end of a slice.
+	resume  chan struct{}
+	yielded chan sliceEnd
+
+	// until is the end of the current quantum, parked is set while the
+	// task waits for its next slice and elapsed is the time spent in the
+	// previous slices. They are only touched by the worker running the
+	// task and by the task during its slice.
+	until   time.Time
+	parked  bool
+	elapsed time.Duration
+}
+
+// sliceEnd reports how a slice ended: done is set once the task returned,
+// possibly by panicking with p.
+type sliceEnd struct {
+	done     bool
+	panicked bool
+	p        any
+}
+
+// AddSliced attaches tasks that share the workers in time slices. Each
+// task is handed a yield function it must call regularly: once the task
+// has run for its quantum, see WithTimeSlice, and other tasks are pending,
+// yield parks it and requeues it behind them, so that long tasks take
+// turns round-robin on few workers. yield returns once the task may go on,
+// and reports false when the run has ended and the task should return. id
+// is the worker that started the task, the following slices may run on
+// other workers. Under WithSynchronous the tasks never yield.
+func (r *Runner) AddSliced(tasks ...func(id int, yield func() bool)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		t := r.push(nil)
+		t.more().slice = &slice{
+			fn:      fn,
+			resume:  make(chan struct{}),
+			yielded: make(chan sliceEnd, 1),
+		}
+		t.efn = func(id int) (any, error) {
+			return r.runSlice(t, id)
+		}
+	}
+}
+
+// runSlice runs the next slice of t on worker id, starting the task on the
+// first one. It returns errYielded when the task yields.
+func (r *Runner) runSlice(t *task, id int) (any, error) {
+	s := t.ext().slice
+	if !s.parked {
+		done := r.Done()
+		r.goTracked(func() { r.sliced(s, id, done) })
+	}
+	quantum := r.timeSlice
+	if quantum <= 0 {
+		quantum = defaultTimeSlice
+	}
+	s.until = time.Now().Add(quantum)
+	s.resume <- struct{}{}
+	end := <-s.yielded
+	s.parked = !end.done
+	if !end.done {
+		return nil, errYielded
+	}
+	if end.panicked {
+		// re-raised on the worker for the panic handler to see
+		panic(end.p)
+	}
+	return nil, nil
+}
+
+// sliced runs the task of s on its own goroutine once the first slice
+// starts.
+func (r *Runner) sliced(s *slice, id int, done <-chan struct{}) {
+	end := sliceEnd{done: true}
+	defer func() {
+		if p := recover(); p != nil {
+			end.panicked, end.p = true, p
+		}
+		s.yielded <- end
+	}()
+	<-s.resume
+	s.fn(id, func() bool {
+		return r.yieldSlice(s, done)
+	})
+}
+
+// yieldSlice parks the task of s until its next slice when its quantum is
+// over and other tasks are pending. It reports false once the run has
+// ended.
+func (r *Runner) yieldSlice(s *slice, done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return false
+	default:
+	}
+	if r.synchronous || time.Now().Before(s.until) {
+		return true
+	}
+	r.m.Lock()
+	pending := r.queue.len()
+	r.m.Unlock()
+	if pending == 0 {
+		return true
+	}
+	s.yielded <- sliceEnd{}
+	select {
+	case <-s.resume:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// reschedule queues the sliced task t again after a slice that took d.
+func (r *Runner) reschedule(t *task, d time.Duration) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	t.ext().slice.elapsed = d
+	r.queue.push(t)
+	r.wakeFor(t)
+}
@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlicedTasksInterleave(t *testing.T) {
+	r := NewWithOptions(time.Second, 1, WithTimeSlice(5*time.Millisecond))
+	var mu sync.Mutex
+	var steps []string
+	for _, name := range []string{"a", "b"} {
+		name := name
+		r.AddSliced(func(id int, yield func() bool) {
+			for i := 0; i < 6; i++ {
+				mu.Lock()
+				steps = append(steps, name)
+				mu.Unlock()
+				time.Sleep(2 * time.Millisecond)
+				if !yield() {
+					return
+				}
+			}
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(steps) != 12 {
+		t.Fatalf("the tasks took %d steps, want 12", len(steps))
+	}
+	switches := 0
+	for i := 1; i < len(steps); i++ {
+		if steps[i] != steps[i-1] {
+			switches++
+		}
+	}
+	if switches < 2 {
+		t.Fatalf("the tasks ran as %v, want them to take turns on the worker", steps)
+	}
+}
@@ -0,0 +1,54 @@
+package runner
+
+import "sort"
+
+// AddStage attaches tasks to the given stage of a pipeline. Stages run in
+// ascending order: the tasks of a stage are held back until every task of
+// the earlier stages and every task added without a stage has finished,
+// then they run in parallel. Ending the run, for instance on a timeout or
+// an interrupt, halts the whole pipeline.
+func (r *Runner) AddStage(stage int, tasks ...func(int)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.held == nil {
+		r.held = make(map[int][]*task)
+	}
+	r.tasks = grow(r.tasks, len(tasks))
+	for _, fn := range tasks {
+		r.held[stage] = append(r.held[stage], r.alloc(Normal, fn))
+	}
+	r.heldCount += len(tasks)
+	r.advanceStage()
+}
+
+// advanceStage releases the tasks of the next stage once the run is in
+// progress and every task that is not held back has finished, r.m must be
+// held.
+func (r *Runner) advanceStage() {
+	if r.heldCount == 0 || !r.dispatching || r.finished < len(r.tasks)-r.heldCount {
+		return
+	}
+	stages := make([]int, 0, len(r.held))
+	for stage := range r.held {
+		stages = append(stages, stage)
+	}
+	sort.Ints(stages)
+	next := r.held[stages[0]]
+	delete(r.held, stages[0])
+	r.heldCount -= len(next)
+	r.queue.reserve(len(next))
+	for _, t := range next {
+		r.queue.push(t)
+		r.wakeFor(t)
+	}
+}
+
+// unhold returns the tasks held back and forgets them, r.m must be held.
+func (r *Runner) unhold() []*task {
+	var tasks []*task
+	for _, stage := range r.held {
+		tasks = append(tasks, stage...)
+	}
+	r.held, r.heldCount = nil, 0
+	return tasks
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStagesRunInOrder(t *testing.T) {
+	r := New(time.Second, 4)
+	var mu sync.Mutex
+	var stage1Done int
+	var stage1Ended, stage2Started time.Time
+	for i := 0; i < 4; i++ {
+		d := time.Duration(i+1) * 5 * time.Millisecond
+		r.AddStage(1, func(int) {
+			time.Sleep(d)
+			mu.Lock()
+			defer mu.Unlock()
+			stage1Done++
+			stage1Ended = time.Now()
+		})
+	}
+	for i := 0; i < 4; i++ {
+		r.AddStage(2, func(int) {
+			mu.Lock()
+			defer mu.Unlock()
+			if stage1Done != 4 {
+				t.Errorf("a stage 2 task started with %d stage 1 tasks done, want 4", stage1Done)
+			}
+			if stage2Started.IsZero() {
+				stage2Started = time.Now()
+			}
+		})
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if stage2Started.Before(stage1Ended) {
+		t.Fatalf("stage 2 started %v before stage 1 ended", stage1Ended.Sub(stage2Started))
+	}
+}
+
+func TestStagesHaltOnTimeout(t *testing.T) {
+	r := New(20*time.Millisecond, 2)
+	r.AddStage(1, blocker(r))
+	ran := false
+	r.AddStage(2, func(int) { ran = true })
+	if err := r.Start(); err != ErrTimeout {
+		t.Fatalf("Start() = %v, want %v", err, ErrTimeout)
+	}
+	if ran {
+		t.Fatal("a stage 2 task ran after the pipeline timed out")
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"log/slog"
+	"time"
+)
+
+// runSync runs the tasks in registration order on the calling goroutine
+// until every task has run or the run ends, and returns the result of the
+// run. It is used by Start in place of the workers and the master goroutine
+// under WithSynchronous.
+func (r *Runner) runSync(done <-chan struct{}) error {
+	if r.workerInit != nil {
+		r.initWorker(0)
+	}
+	for i := 0; ; {
+		select {
+		case err := <-r.completeMain:
+			return err
+		case sig := <-r.interrupt:
+			r.interrupted(sig)
+			continue
+		default:
+		}
+		now := time.Now()
+		if now.Sub(r.timeline.DispatchStart) >= r.timeoutDuration {
+			r.end(ErrTimeout)
+			continue
+		}
+		if !r.deadline.IsZero() && !now.Before(r.deadline) {
+			r.end(ErrDeadline)
+			continue
+		}
+		if !r.softDeadline.IsZero() && !now.Before(r.softDeadline) {
+			r.end(ErrTimeout)
+			continue
+		}
+		r.m.Lock()
+		r.release(0)
+		if r.workBudget > 0 && r.workSpent > r.workBudget {
+			i = len(r.tasks)
+		} else if i == len(r.tasks) && r.queue.len() > 0 {
+			// tasks queued again or released by a stage are picked
+			// up by another pass
+			i = 0
+		}
+		if i == len(r.tasks) && r.source != nil && !r.sourceDrained {
+			r.m.Unlock()
+			fn, ok := r.source.Next()
+			r.m.Lock()
+			if ok {
+				r.push(fn)
+			} else {
+				r.sourceDrained = true
+			}
+		}
+		if i == len(r.tasks) {
+			r.dispatching = false
+			r.timeline.ShutdownComplete = time.Now()
+			r.m.Unlock()
+			r.end(r.finalErr())
+			continue
+		}
+		t := r.tasks[i]
+		i++
+		// canceled tasks are no longer queued
+		if !r.queue.remove(t) {
+			r.m.Unlock()
+			continue
+		}
+		r.handOut(t, 0)
+		r.m.Unlock()
+		r.log("task started", slog.Int("task", t.index), slog.Int("worker", 0))
+		res := r.execute(t, 0, done)
+		r.spend(res.Duration)
+		r.finish(t, res, time.Now())
+		r.logFinished(t, 0, res)
+	}
+}
@@ -0,0 +1,511 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// taskChunkSize is the number of tasks allocated at once by push.
+const taskChunkSize = 64
+
+// closedChan is handed out by Done for tasks that are already done.
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+}
+
+// task is a unit of work registered on the Runner.
+type task struct {
+	// index is the registration index of the task.
+	index int
+
+	// fn is the work itself, efn replaces it for tasks that produce a
+	// value or can fail.
+	fn  func(int)
+	efn func(int) (any, error)
+
+	// extra holds the state of the less common kinds of tasks, nil for
+	// plain ones so that they stay small.
+	extra *taskExtra
+
+	// ran is set once the task has run and result holds its outcome,
+	// guarded by Runner.m.
+	ran    bool
+	result TaskResult
+
+	// restored marks a task skipped because an earlier run completed
+	// it, see SkipCompleted.
+	restored bool
+
+	// ended is the time the task finished running, guarded by
+	// Runner.m.
+	ended time.Time
+
+	// attempt is the number of the attempt in progress, starting at 1.
+	// It is only touched by the worker running the task.
+	attempt int
+
+	// priority orders the dispatch of the task.
+	priority Priority
+
+	// cancelable marks a context-aware task. canceled is set by
+	// CancelTask and cancel releases the context of the running task,
+	// both guarded by Runner.m.
+	cancelable bool
+	canceled   bool
+	cancel     func()
+
+	// preempted is set when the task was canceled to free its worker for
+	// a Critical task, guarded by Runner.m.
+	preempted bool
+
+	// progress is the last fraction reported by the task, guarded by
+	// Runner.m.
+	progress float64
+
+	// warnings holds the warnings reported by the task, guarded by
+	// Runner.m.
+	warnings []string
+
+	// finished is set once the task has finished or was canceled.
+	finished bool
+
+	// done is closed when finished is set. It is only allocated when
+	// somebody waits on the task, guarded by Runner.m.
+	done chan struct{}
+}
+
+// taskExtra is the part of a task only some kinds of tasks use.
+type taskExtra struct {
+	// descriptor is the ID of the descriptor of a persisted task, empty
+	// for the others.
+	descriptor string
+
+	// group is the name of the group of the task, see AddGroup.
+	group string
+
+	// sequence is the name of the sequential group of the task, see
+	// AddSequentialGroup.
+	sequence string
+
+	// key identifies the result of a keyed task in the result cache.
+	keyed bool
+	key   string
+
+	// retry overrides the retry policy of the runner, see AddWithRetry.
+	retry *RetryPolicy
+
+	// estimate is the expected duration of the task, zero when unknown.
+	estimate time.Duration
+
+	// affine marks a task that may only run on the worker with id worker.
+	affine bool
+	worker int
+
+	// reserved marks a slot registered with Reserve that has not started
+	// yet and filled is the function it was filled with, guarded by
+	// Runner.m.
+	reserved bool
+	filled   func(int)
+
+	// slice holds the state of a task added with AddSliced.
+	slice *slice
+}
+
+// noExtra stands in for the extra state of plain tasks. It is never
+// written to.
+var noExtra taskExtra
+
+// ext returns the extra state of t, or noExtra for a plain task. Use more
+// to change it.
+func (t *task) ext() *taskExtra {
+	if t.extra == nil {
+		return &noExtra
+	}
+	return t.extra
+}
+
+// more returns the extra state of t, allocating it on first use. The
+// first call must come while the task is being registered, before a worker
+// can see it.
+func (t *task) more() *taskExtra {
+	if t.extra == nil {
+		t.extra = &taskExtra{}
+	}
+	return t.extra
+}
+
+// run executes the task on worker id.
+func (t *task) run(id int) (any, error) {
+	if t.efn != nil {
+		return t.efn(id)
+	}
+	t.fn(id)
+	return nil, nil
+}
+
+// close marks t as finished, Runner.m must be held.
+func (t *task) close(r *Runner) {
+	r.finished++
+	t.finished = true
+	if t.done != nil {
+		close(t.done)
+	}
+	r.advanceStage()
+	if t.ext().sequence != "" {
+		r.advanceSequence(t)
+	}
+	// the workers waiting for a task exit once no work is outstanding
+	if r.finished == len(r.tasks) {
+		r.cond.Broadcast()
+	}
+}
+
+// Requeue queues the finished task registered at index to run again in the
+// current run, clearing its result, progress and warnings. It reports false
+// when the run is not in progress, when there is no such task or when it
+// has not finished yet. The task is outstanding again until it has rerun,
+// so the run waits for it. Requeue fails once the workers have started
+// exiting because no work was left, unless idle workers are respawned, see
+// WithIdleTimeout.
+func (r *Runner) Requeue(index int) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if !r.dispatching || (r.live == 0 && r.idleTimeout <= 0) {
+		return false
+	}
+	if index < 0 || index >= len(r.tasks) {
+		return false
+	}
+	t := r.tasks[index]
+	if !t.finished {
+		return false
+	}
+	if t.ran {
+		if r.failure(t.result) {
+			r.failed--
+		} else if t.result.Err == nil {
+			r.succeeded--
+		}
+	}
+	r.finished--
+	t.ran, t.result, t.ended, t.attempt = false, TaskResult{}, time.Time{}, 0
+	t.finished, t.canceled, t.restored, t.done = false, false, false, nil
+	t.progress, t.warnings = 0, nil
+	r.queue.push(t)
+	r.wakeFor(t)
+	return true
+}
+
+// TaskHandle refers to a single task registered with AddHandle.
+type TaskHandle struct {
+	r *Runner
+	t *task
+}
+
+// AddHandle attaches a single task to the Runner and returns a handle
+// that can be used to cancel it while it is still pending.
+func (r *Runner) AddHandle(fn func(int)) TaskHandle {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return TaskHandle{r: r, t: r.push(fn)}
+}
+
+// AddAffinity attaches a task that only the worker with id workerID runs.
+// Other workers skip it. AddAffinity panics if workerID is not the id of
+// one of the workers.
+func (r *Runner) AddAffinity(workerID int, fn func(int)) {
+	if workerID < 0 || workerID >= r.numberOfWorker {
+		panic(fmt.Sprintf("runner: affinity to unknown worker %d", workerID))
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	t := r.push(fn)
+	x := t.more()
+	x.affine = true
+	x.worker = workerID
+}
+
+// AddEstimated attaches a task expected to take about est. When a worker
+// gets to it with less than est left before the run's timeout or deadline,
+// the task is skipped rather than started, and recorded with ErrTaskSkipped.
+func (r *Runner) AddEstimated(est time.Duration, fn func(int)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.push(fn).more().estimate = est
+}
+
+// Index returns the registration index of the task.
+func (h TaskHandle) Index() int {
+	return h.t.index
+}
+
+// Cancel removes the task from the queue if no worker has picked it up yet
+// and reports whether it was removed. A canceled task never runs.
+func (h TaskHandle) Cancel() bool {
+	r := h.r
+	r.m.Lock()
+	defer r.m.Unlock()
+	if !r.queue.remove(h.t) {
+		return false
+	}
+	h.t.close(r)
+	r.releaseFlush()
+	return true
+}
+
+// Done returns a channel that is closed once the task has finished running
+// or has been canceled.
+func (h TaskHandle) Done() <-chan struct{} {
+	h.r.m.Lock()
+	defer h.r.m.Unlock()
+	switch {
+	case h.t.done != nil:
+	case h.t.finished:
+		return closedChan
+	default:
+		h.t.done = make(chan struct{})
+	}
+	return h.t.done
+}
+
+// WaitTask blocks until the task of handle has finished or the run has
+// ended, and returns the task's result. It returns ErrTaskCanceled if the
+// task was canceled before running and ErrTaskNotRun if the run ended
+// before the task finished.
+func (r *Runner) WaitTask(handle TaskHandle) (TaskResult, error) {
+	done := handle.Done()
+	r.m.Lock()
+	runDone := r.done
+	r.m.Unlock()
+	select {
+	case <-done:
+	case <-runDone:
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	t := handle.t
+	switch {
+	case t.ran:
+		return t.result, nil
+	case t.finished:
+		return TaskResult{Index: t.index}, ErrTaskCanceled
+	}
+	return TaskResult{Index: t.index}, ErrTaskNotRun
+}
+
+// AddWithProgress attaches tasks that report their own progress. Each task
+// is handed a report function accepting a fraction between 0 and 1, which
+// feeds into OverallProgress.
+func (r *Runner) AddWithProgress(tasks ...func(id int, report func(fraction float64))) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		t := r.push(nil)
+		report := func(fraction float64) {
+			r.report(t, fraction)
+		}
+		t.fn = func(id int) {
+			fn(id, report)
+		}
+	}
+}
+
+// report records the progress of t, clamped to [0, 1].
+func (r *Runner) report(t *task, fraction float64) {
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if !t.finished {
+		t.progress = fraction
+	}
+}
+
+// OverallProgress returns the progress of the registered tasks as a whole,
+// between 0 and 1. Finished tasks count as 1, running tasks count as their
+// last reported fraction and tasks that do not report count as 0 until they
+// finish.
+func (r *Runner) OverallProgress() float64 {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if len(r.tasks) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, t := range r.tasks {
+		if t.finished {
+			sum++
+		} else {
+			sum += t.progress
+		}
+	}
+	return sum / float64(len(r.tasks))
+}
+
+// AddWithWarnings attaches tasks that may report non-fatal warnings. Each
+// task is handed a warn function, the warnings it reports are collected
+// and returned by Warnings. Warnings do not make a task fail.
+func (r *Runner) AddWithWarnings(tasks ...func(id int, warn func(string))) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		t := r.push(nil)
+		warn := func(msg string) {
+			r.m.Lock()
+			defer r.m.Unlock()
+			t.warnings = append(t.warnings, msg)
+		}
+		t.fn = func(id int) {
+			fn(id, warn)
+		}
+	}
+}
+
+// Warnings returns the warnings reported so far, keyed by the registration
+// index of the task that reported them. Tasks without warnings are left
+// out.
+func (r *Runner) Warnings() map[int][]string {
+	r.m.Lock()
+	defer r.m.Unlock()
+	warnings := make(map[int][]string)
+	for _, t := range r.tasks {
+		if len(t.warnings) > 0 {
+			warnings[t.index] = append([]string(nil), t.warnings...)
+		}
+	}
+	return warnings
+}
+
+// AddWithCPUReport attaches tasks that measure their own CPU time. Each
+// task is handed a report function, the CPU time it reports is added up
+// across tasks and returned by TotalReportedCPU. Unlike the durations of
+// the results, this is not wall time and relies on the tasks measuring it.
+func (r *Runner) AddWithCPUReport(tasks ...func(id int, report func(cpu time.Duration))) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		r.push(func(id int) {
+			fn(id, r.reportCPU)
+		})
+	}
+}
+
+// reportCPU adds cpu to the CPU time reported by the tasks.
+func (r *Runner) reportCPU(cpu time.Duration) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.reportedCPU += cpu
+}
+
+// TotalReportedCPU returns the CPU time reported so far by the tasks added
+// with AddWithCPUReport in the current or last run.
+func (r *Runner) TotalReportedCPU() time.Duration {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.reportedCPU
+}
+
+// Reserve registers n task slots to be filled later with Fill, and returns
+// the index of the first one, the others following it. A slot left empty
+// by the time it runs runs the default task set with WithDefaultTask, if
+// any, so that every slot of a fixed grid runs exactly once.
+func (r *Runner) Reserve(n int) int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	first := len(r.tasks)
+	r.tasks = grow(r.tasks, n)
+	r.queue.reserve(n)
+	for i := 0; i < n; i++ {
+		t := r.push(nil)
+		x := t.more()
+		x.reserved = true
+		t.fn = func(id int) {
+			r.m.Lock()
+			fn := x.filled
+			x.reserved = false
+			r.m.Unlock()
+			if fn == nil {
+				fn = r.defaultTask
+			}
+			if fn != nil {
+				fn(id)
+			}
+		}
+	}
+	return first
+}
+
+// Fill sets the function of the slot registered with Reserve at index. It
+// reports false when there is no such slot or it has already started.
+func (r *Runner) Fill(index int, fn func(int)) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if index < 0 || index >= len(r.tasks) || !r.tasks[index].ext().reserved {
+		return false
+	}
+	r.tasks[index].more().filled = fn
+	return true
+}
+
+// TaskID identifies a task by its registration index. Unlike the int passed
+// to plain tasks, which is the id of the worker running them, a TaskID is
+// unique to the task.
+type TaskID int
+
+// String returns the id in the form "task-<index>".
+func (id TaskID) String() string {
+	return "task-" + strconv.Itoa(int(id))
+}
+
+// AddWithID attaches tasks that are passed their own TaskID instead of the
+// worker id.
+func (r *Runner) AddWithID(tasks ...func(TaskID)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		t := r.push(nil)
+		t.fn = func(int) {
+			fn(TaskID(t.index))
+		}
+	}
+}
+
+// TaskSource produces tasks on demand. Next returns the next task, or false
+// once the source is exhausted. Next is never called concurrently, but it
+// may block until a task is available.
+type TaskSource interface {
+	Next() (func(int), bool)
+}
+
+// FromSlice returns one task per item of items, each calling fn with the
+// worker id and its own item:
+//
+//	r.Add(runner.FromSlice(users, handleUser)...)
+func FromSlice[T any](items []T, fn func(int, T)) []func(int) {
+	tasks := make([]func(int), len(items))
+	for i := range items {
+		item := items[i]
+		tasks[i] = func(id int) {
+			fn(id, item)
+		}
+	}
+	return tasks
+}
@@ -0,0 +1,372 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskHandleCancel(t *testing.T) {
+	r := New(time.Second, 1)
+	var ran [3]atomic.Bool
+	handles := make([]TaskHandle, 3)
+	for i := range handles {
+		i := i
+		handles[i] = r.AddHandle(func(int) { ran[i].Store(true) })
+	}
+	if !handles[1].Cancel() {
+		t.Fatal("Cancel() = false for a pending task")
+	}
+	if handles[1].Cancel() {
+		t.Fatal("Cancel() = true for a task already canceled")
+	}
+	select {
+	case <-handles[1].Done():
+	default:
+		t.Fatal("Done() not closed for a canceled task")
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if !ran[0].Load() || ran[1].Load() || !ran[2].Load() {
+		t.Fatalf("ran = %v, %v, %v; want true, false, true", ran[0].Load(), ran[1].Load(), ran[2].Load())
+	}
+	for i, h := range handles {
+		select {
+		case <-h.Done():
+		default:
+			t.Fatalf("Done() not closed for task %d", i)
+		}
+	}
+	if handles[0].Cancel() {
+		t.Fatal("Cancel() = true for a task that has run")
+	}
+}
+
+func TestOverallProgress(t *testing.T) {
+	r := New(time.Second, 2)
+	reported := make(chan struct{})
+	release := make(chan struct{})
+	r.AddWithProgress(func(id int, report func(float64)) {
+		report(0.5)
+		close(reported)
+		<-release
+	})
+	r.Add(func(int) {})
+	if p := r.OverallProgress(); p != 0 {
+		t.Fatalf("OverallProgress() = %v before the run, want 0", p)
+	}
+	done := make(chan error)
+	go func() { done <- r.Start() }()
+	<-reported
+	// wait for the plain task to finish, it then counts as 1
+	deadline := time.Now().Add(time.Second)
+	for r.OverallProgress() < 0.75 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if p := r.OverallProgress(); p != 0.75 {
+		t.Fatalf("OverallProgress() = %v, want 0.75", p)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if p := r.OverallProgress(); p != 1 {
+		t.Fatalf("OverallProgress() = %v after the run, want 1", p)
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	r := New(time.Second, 3)
+	var mu sync.Mutex
+	ranOn := make(map[int]int)
+	for i := 0; i < 20; i++ {
+		r.AddAffinity(1, func(id int) {
+			mu.Lock()
+			ranOn[id]++
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+		})
+		r.Add(func(int) { time.Sleep(time.Millisecond) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(ranOn) != 1 || ranOn[1] != 20 {
+		t.Fatalf("affine tasks ran on %v, want all 20 on worker 1", ranOn)
+	}
+}
+
+func TestAffinityUnknownWorker(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("AddAffinity did not panic for an unknown worker")
+		}
+	}()
+	New(time.Second, 2).AddAffinity(2, func(int) {})
+}
+
+func TestTaskIDsAreUnique(t *testing.T) {
+	r := New(time.Second, 4)
+	var mu sync.Mutex
+	seen := make(map[TaskID]bool)
+	r.Add(func(int) {})
+	var tasks []func(TaskID)
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, func(id TaskID) {
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[id] {
+				t.Errorf("TaskID %v handed out twice", id)
+			}
+			seen[id] = true
+		})
+	}
+	r.AddWithID(tasks...)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(seen) != 20 {
+		t.Fatalf("%d distinct TaskIDs, want 20", len(seen))
+	}
+	for id := range seen {
+		if id < 1 || id > 20 {
+			t.Fatalf("TaskID %v is not a registration index", id)
+		}
+	}
+}
+
+func TestTaskIDString(t *testing.T) {
+	if s := TaskID(7).String(); s != "task-7" {
+		t.Fatalf("String() = %q, want task-7", s)
+	}
+}
+
+// countingSource yields n tasks, recording how far ahead of the finished
+// tasks it was pulled.
+type countingSource struct {
+	mu       sync.Mutex
+	n        int
+	pulled   int
+	finished int
+	ahead    int
+}
+
+func (s *countingSource) Next() (func(int), bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pulled == s.n {
+		return nil, false
+	}
+	s.pulled++
+	if a := s.pulled - s.finished; a > s.ahead {
+		s.ahead = a
+	}
+	return func(int) {
+		s.mu.Lock()
+		s.finished++
+		s.mu.Unlock()
+	}, true
+}
+
+func TestSourcePulledLazily(t *testing.T) {
+	src := &countingSource{n: 100}
+	r := NewWithOptions(5*time.Second, 2, WithSource(src))
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if src.finished != 100 {
+		t.Fatalf("%d tasks ran, want 100", src.finished)
+	}
+	// a task is pulled only when a worker has none to run
+	if src.ahead > 3 {
+		t.Fatalf("source pulled %d tasks ahead of the workers", src.ahead)
+	}
+}
+
+func TestFromSlice(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	r := New(time.Second, 3)
+	r.Add(FromSlice(items, func(id int, item string) {
+		mu.Lock()
+		seen[item]++
+		mu.Unlock()
+	})...)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if len(seen) != len(items) {
+		t.Fatalf("processed %v, want each of %v", seen, items)
+	}
+	for _, item := range items {
+		if seen[item] != 1 {
+			t.Fatalf("item %q processed %d times, want once", item, seen[item])
+		}
+	}
+}
+
+func TestWaitTask(t *testing.T) {
+	r := New(time.Second, 2)
+	var handles []TaskHandle
+	for i := 0; i < 5; i++ {
+		d := time.Duration(i) * 10 * time.Millisecond
+		handles = append(handles, r.AddHandle(func(int) { time.Sleep(d) }))
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start() }()
+	res, err := r.WaitTask(handles[3])
+	if err != nil {
+		t.Fatalf("WaitTask() = %v, want nil", err)
+	}
+	if res.Index != handles[3].Index() {
+		t.Fatalf("WaitTask() returned the result of task %d, want %d", res.Index, handles[3].Index())
+	}
+	if res.Duration < 30*time.Millisecond {
+		t.Fatalf("WaitTask() returned after %v of work, want at least 30ms", res.Duration)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+}
+
+func TestAddEstimatedSkipsLongTasks(t *testing.T) {
+	r := New(100*time.Millisecond, 1)
+	var ran atomic.Bool
+	r.AddEstimated(time.Second, func(int) { ran.Store(true) })
+	r.AddEstimated(time.Millisecond, func(int) {})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if ran.Load() {
+		t.Fatal("the task estimated past the timeout ran")
+	}
+	res := r.Results()
+	if !res[0].Skipped || res[0].Err != ErrTaskSkipped {
+		t.Fatalf("Results()[0] = %+v, want it skipped with %v", res[0], ErrTaskSkipped)
+	}
+	if res[1].Skipped || res[1].Err != nil {
+		t.Fatalf("Results()[1] = %+v, want it run", res[1])
+	}
+}
+
+func TestWarningsAreNotFailures(t *testing.T) {
+	r := New(time.Second, 1)
+	r.AddWithWarnings(func(id int, warn func(string)) {
+		warn("disk almost full")
+		warn("slow response")
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	got := r.Warnings()[0]
+	if len(got) != 2 || got[0] != "disk almost full" || got[1] != "slow response" {
+		t.Fatalf("Warnings()[0] = %q, want both warnings in order", got)
+	}
+	if err := r.Results()[0].Err; err != nil {
+		t.Fatalf("task error = %v, want the warnings not to fail it", err)
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	r := New(time.Second, 2)
+	var runs atomic.Int32
+	first := r.AddHandle(func(int) { runs.Add(1) })
+	var requeued bool
+	r.Add(func(int) {
+		<-first.Done()
+		requeued = r.Requeue(first.Index())
+	})
+	if r.Requeue(first.Index()) {
+		t.Fatal("Requeue() = true before the run, want false")
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if !requeued {
+		t.Fatal("Requeue() = false for a finished task mid-run, want true")
+	}
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("the requeued task ran %d times, want 2", got)
+	}
+	if m := r.Metrics(); m.Succeeded != 2 {
+		t.Fatalf("Metrics().Succeeded = %d, want 2", m.Succeeded)
+	}
+	if r.Requeue(first.Index()) {
+		t.Fatal("Requeue() = true after the run, want false")
+	}
+}
+
+func TestRequeueFromAggregator(t *testing.T) {
+	var r *Runner
+	var requeued bool
+	agg := NewAggregator(0, func(n int, res TaskResult) int {
+		if res.Index == 0 && !requeued {
+			requeued = r.Requeue(0)
+		}
+		return n + 1
+	})
+	r = NewWithOptions(time.Second, 2, WithAggregator(agg))
+	var runs atomic.Int32
+	rerun := make(chan struct{})
+	r.AddWithWarnings(func(id int, warn func(string)) {
+		if runs.Add(1) == 1 {
+			warn("first run")
+			return
+		}
+		close(rerun)
+	})
+	// keeps a worker busy until the rerun
+	r.Add(func(int) { <-rerun })
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if !requeued {
+		t.Fatal("Requeue() = false from the aggregator, want true")
+	}
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("the requeued task ran %d times, want 2", got)
+	}
+	if w := r.Warnings()[0]; len(w) != 0 {
+		t.Fatalf("Warnings()[0] = %q after the rerun, want the first run's cleared", w)
+	}
+	if m := r.Metrics(); m.Succeeded != 2 || m.Pending != 0 {
+		t.Fatalf("Metrics() = %+v, want both tasks counted once", m)
+	}
+}
+
+func TestTotalReportedCPU(t *testing.T) {
+	r := New(time.Second, 3)
+	for _, cpu := range []time.Duration{10, 20, 30} {
+		cpu := cpu * time.Millisecond
+		r.AddWithCPUReport(func(id int, report func(time.Duration)) { report(cpu) })
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := r.TotalReportedCPU(); got != 60*time.Millisecond {
+		t.Fatalf("TotalReportedCPU() = %v, want 60ms", got)
+	}
+}
+
+func TestDefaultTaskFillsReservedSlots(t *testing.T) {
+	var filled, defaulted atomic.Int32
+	r := NewWithOptions(time.Second, 2, WithDefaultTask(func(int) { defaulted.Add(1) }))
+	first := r.Reserve(5)
+	for i := 0; i < 3; i++ {
+		if !r.Fill(first+i, func(int) { filled.Add(1) }) {
+			t.Fatalf("Fill(%d) = false, want true", first+i)
+		}
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if got := filled.Load(); got != 3 {
+		t.Fatalf("%d filled slots ran, want 3", got)
+	}
+	if got := defaulted.Load(); got != 2 {
+		t.Fatalf("the default task ran %d times, want 2", got)
+	}
+}
@@ -0,0 +1,49 @@
+package runner
+
+// AddGroup attaches fallible tasks as members of the named group. The
+// results of each group are scored together by the scorer set with
+// WithGroupScorer, see GroupScore. A task that returns a non-nil error
+// counts as failed, as with AddFallible.
+func (r *Runner) AddGroup(name string, tasks ...func(int) error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.tasks = grow(r.tasks, len(tasks))
+	r.queue.reserve(len(tasks))
+	for _, fn := range tasks {
+		fn := fn
+		t := r.push(nil)
+		t.more().group = name
+		t.efn = func(id int) (any, error) {
+			return nil, fn(id)
+		}
+	}
+}
+
+// GroupScore returns the score of each group of tasks added with AddGroup,
+// computed by the scorer set with WithGroupScorer from the results of the
+// members that have run so far. It returns nil without a scorer.
+func (r *Runner) GroupScore() map[string]float64 {
+	if r.groupScorer == nil {
+		return nil
+	}
+	r.m.Lock()
+	groups := make(map[string][]TaskResult)
+	for _, t := range r.tasks {
+		name := t.ext().group
+		if name == "" {
+			continue
+		}
+		if _, ok := groups[name]; !ok {
+			groups[name] = nil
+		}
+		if t.ran {
+			groups[name] = append(groups[name], t.result)
+		}
+	}
+	r.m.Unlock()
+	scores := make(map[string]float64, len(groups))
+	for name, results := range groups {
+		scores[name] = r.groupScorer(name, results)
+	}
+	return scores
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupScore(t *testing.T) {
+	weights := map[string]float64{"api": 2, "db": 0.5}
+	r := NewWithOptions(time.Second, 2, WithGroupScorer(func(name string, results []TaskResult) float64 {
+		succeeded := 0
+		for _, res := range results {
+			if res.Err == nil {
+				succeeded++
+			}
+		}
+		return weights[name] * float64(succeeded) / float64(len(results))
+	}))
+	ok := func(int) error { return nil }
+	fail := func(int) error { return errors.New("boom") }
+	r.AddGroup("api", ok, ok, ok, fail)
+	r.AddGroup("db", ok, fail)
+	r.Start()
+	got := r.GroupScore()
+	want := map[string]float64{"api": 1.5, "db": 0.25}
+	if len(got) != len(want) || got["api"] != want["api"] || got["db"] != want["db"] {
+		t.Fatalf("GroupScore() = %v, want %v", got, want)
+	}
+}
+
+func TestGroupScoreWithoutScorer(t *testing.T) {
+	r := New(time.Second, 1)
+	r.AddGroup("api", func(int) error { return nil })
+	r.Start()
+	if got := r.GroupScore(); got != nil {
+		t.Fatalf("GroupScore() = %v without a scorer, want nil", got)
+	}
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTaskTimeout is recorded for a task added with AddWithTimeout that ran
+// past its own timeout. It is distinct from ErrTimeout, which Start returns
+// when the run as a whole runs out of time. Such a task counts as failed
+// unless WithTaskTimeoutSoft is set.
+var ErrTaskTimeout = errors.New("task timed out")
+
+// AddWithTimeout attaches a context-aware task that has d to run. The
+// task's context is canceled once d has elapsed and the task is expected to
+// return promptly when it is; the worker waits for it to do so. Before
+// returning the task can record a partial result through setPartial. When
+// the task timed out, its TaskResult carries the last partial value along
+// with ErrTaskTimeout. Like the tasks added with AddContextTask, the task
+// can be canceled with CancelTask.
+func (r *Runner) AddWithTimeout(d time.Duration, fn func(ctx context.Context, id int, setPartial func(v any)) (any, error)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	t := r.push(nil)
+	t.cancelable = true
+	t.efn = func(id int) (any, error) {
+		parent, release := r.taskContext(t)
+		defer release()
+		ctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+		var partial any
+		v, err := fn(ctx, id, func(v any) {
+			partial = v
+		})
+		if ctx.Err() == context.DeadlineExceeded {
+			return partial, ErrTaskTimeout
+		}
+		if cerr := r.canceledErr(t); cerr != nil {
+			return v, cerr
+		}
+		return v, err
+	}
+}
+
+// AddWithSLA attaches a context-aware task held to a service level: once it
+// has run for warnAfter the callback registered with OnSLAWarning is
+// called, and once it has run for killAfter its context is canceled and it
+// is recorded with ErrTaskTimeout, as with AddWithTimeout. The warning
+// leaves operators time to react before the task is cut short. Like the
+// tasks added with AddContextTask, the task can be canceled with
+// CancelTask.
+func (r *Runner) AddWithSLA(warnAfter, killAfter time.Duration, fn func(ctx context.Context, id int)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	t := r.push(nil)
+	t.cancelable = true
+	t.efn = func(id int) (any, error) {
+		parent, release := r.taskContext(t)
+		defer release()
+		ctx, cancel := context.WithTimeout(parent, killAfter)
+		defer cancel()
+		if r.slaWarning != nil {
+			warn := time.AfterFunc(warnAfter, func() {
+				r.slaWarning(t.index, warnAfter)
+			})
+			defer warn.Stop()
+		}
+		fn(ctx, id)
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrTaskTimeout
+		}
+		return nil, r.canceledErr(t)
+	}
+}
@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTaskTimeoutKeepsPartialResult(t *testing.T) {
+	r := New(time.Second, 1)
+	r.AddWithTimeout(20*time.Millisecond, func(ctx context.Context, id int, setPartial func(any)) (any, error) {
+		setPartial(42)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	r.AddWithTimeout(time.Second, func(ctx context.Context, id int, setPartial func(any)) (any, error) {
+		setPartial(1)
+		return 2, nil
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	results := r.Results()
+	if res := results[0]; !errors.Is(res.Err, ErrTaskTimeout) || res.Value != 42 {
+		t.Fatalf("timed out task result = %v, %v; want 42, %v", res.Value, res.Err, ErrTaskTimeout)
+	}
+	if res := results[1]; res.Err != nil || res.Value != 2 {
+		t.Fatalf("task result = %v, %v; want 2, nil", res.Value, res.Err)
+	}
+}
+
+func TestTaskTimeoutIsNotRunTimeout(t *testing.T) {
+	r := New(time.Second, 1)
+	r.AddWithTimeout(10*time.Millisecond, func(ctx context.Context, id int, setPartial func(any)) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if err := r.Results()[0].Err; !errors.Is(err, ErrTaskTimeout) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("task error = %v, want %v only", err, ErrTaskTimeout)
+	}
+}
+
+func TestSoftDeadlineGrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     time.Duration
+		finished bool
+	}{
+		{"finishes within grace", 40 * time.Millisecond, true},
+		{"abandoned after grace", time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			r := NewWithOptions(5*time.Second, 1,
+				WithSoftDeadline(start.Add(20*time.Millisecond), 60*time.Millisecond))
+			finished := make(chan struct{})
+			r.Add(func(int) {
+				time.Sleep(tt.task)
+				close(finished)
+			})
+			ran := false
+			r.Add(func(int) { ran = true })
+			if err := r.Start(); err != ErrTimeout {
+				t.Fatalf("Start() = %v, want %v", err, ErrTimeout)
+			}
+			if d := time.Since(start); d > 500*time.Millisecond {
+				t.Fatalf("Start() returned after %v, want within the grace", d)
+			}
+			select {
+			case <-finished:
+				if !tt.finished {
+					t.Fatal("the in-flight task finished, want it abandoned")
+				}
+			default:
+				if tt.finished {
+					t.Fatal("Start() returned before the in-flight task finished")
+				}
+			}
+			if ran {
+				t.Fatal("a task started after the soft deadline")
+			}
+		})
+	}
+}
+
+func TestTaskTimeoutSoft(t *testing.T) {
+	for _, soft := range []bool{false, true} {
+		opts := []Option{WithConcurrentFailureLimit(2)}
+		if soft {
+			opts = append(opts, WithTaskTimeoutSoft())
+		}
+		r := NewWithOptions(time.Second, 2, opts...)
+		for i := 0; i < 2; i++ {
+			r.AddWithTimeout(10*time.Millisecond, func(ctx context.Context, id int, setPartial func(any)) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+		}
+		err := r.Start()
+		if !soft {
+			if err != ErrConcurrentFailures {
+				t.Fatalf("Start() = %v, want %v for hard timeouts", err, ErrConcurrentFailures)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Start() = %v, want nil for soft timeouts", err)
+		}
+		if m := r.Metrics(); m.Failed != 0 {
+			t.Fatalf("Metrics().Failed = %d, want soft timeouts not to count", m.Failed)
+		}
+		for _, res := range r.Results() {
+			if !errors.Is(res.Err, ErrTaskTimeout) {
+				t.Fatalf("result error = %v, want %v", res.Err, ErrTaskTimeout)
+			}
+		}
+	}
+}
+
+func TestSLAWarnsThenCancels(t *testing.T) {
+	r := New(time.Second, 1)
+	warned := make(chan time.Duration, 1)
+	r.OnSLAWarning(func(index int, elapsed time.Duration) { warned <- elapsed })
+	var warnedFirst bool
+	r.AddWithSLA(10*time.Millisecond, 40*time.Millisecond, func(ctx context.Context, id int) {
+		<-ctx.Done()
+		select {
+		case <-warned:
+			warnedFirst = true
+		default:
+		}
+	})
+	r.AddWithSLA(time.Second, 2*time.Second, func(ctx context.Context, id int) {})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	if !warnedFirst {
+		t.Fatal("the task was canceled without an SLA warning first")
+	}
+	res := r.Results()
+	if !errors.Is(res[0].Err, ErrTaskTimeout) || res[0].Duration < 40*time.Millisecond {
+		t.Fatalf("Results()[0] = %+v, want %v after 40ms", res[0], ErrTaskTimeout)
+	}
+	if res[1].Err != nil {
+		t.Fatalf("Results()[1] = %+v, want the fast task unaffected", res[1])
+	}
+	select {
+	case <-warned:
+		t.Fatal("the fast task triggered an SLA warning")
+	default:
+	}
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"sync"
+	"time"
+)
+
+// TraceStep is a dispatch decision: task Task was handed to worker Worker,
+// At after the run started.
+type TraceStep struct {
+	Task   int
+	Worker int
+	At     time.Duration
+}
+
+// Trace records the dispatch decisions of a run, see RecordTrace, and can
+// force them on another run, see WithReplayTrace. It is safe for
+// concurrent use.
+type Trace struct {
+	m     sync.Mutex
+	steps []TraceStep
+}
+
+// NewTrace returns a Trace made of steps, for instance to replay a trace
+// that was saved.
+func NewTrace(steps []TraceStep) *Trace {
+	return &Trace{steps: append([]TraceStep(nil), steps...)}
+}
+
+// Steps returns the steps of the trace in dispatch order.
+func (tr *Trace) Steps() []TraceStep {
+	tr.m.Lock()
+	defer tr.m.Unlock()
+	return append([]TraceStep(nil), tr.steps...)
+}
+
+// add appends s to the trace.
+func (tr *Trace) add(s TraceStep) {
+	tr.m.Lock()
+	defer tr.m.Unlock()
+	tr.steps = append(tr.steps, s)
+}
+
+// step returns the step at i, if any.
+func (tr *Trace) step(i int) (TraceStep, bool) {
+	tr.m.Lock()
+	defer tr.m.Unlock()
+	if i >= len(tr.steps) {
+		return TraceStep{}, false
+	}
+	return tr.steps[i], true
+}
+
+// RecordTrace starts recording the dispatch decisions of the runner and
+// returns the trace they are recorded into, which grows as the run
+// proceeds. It must be called before Start.
+func (r *Runner) RecordTrace() *Trace {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.trace = &Trace{}
+	return r.trace
+}
+
+// take returns the next task worker id may run, or nil if there is none
+// for it yet. While a trace is replayed, that is the task of the next step
+// if the step is for id, r.m must be held.
+func (r *Runner) take(id int) *task {
+	for r.replay != nil {
+		s, ok := r.replay.step(r.replayed)
+		if !ok {
+			break
+		}
+		if (s.Worker < r.numberOfWorker && s.Worker != id) || s.Task >= len(r.tasks) {
+			return nil
+		}
+		t := r.tasks[s.Task]
+		if !r.queue.remove(t) {
+			if !t.finished {
+				// not queued yet
+				return nil
+			}
+			// canceled meanwhile
+			r.replayed++
+			continue
+		}
+		r.replayed++
+		// wake up the worker of the next step
+		r.cond.Broadcast()
+		return t
+	}
+	return r.queue.take(id)
+}
+
+// handOut records that t was handed to worker id, r.m must be held.
+func (r *Runner) handOut(t *task, id int) {
+	now := time.Now()
+	if r.timeline.FirstTaskStart.IsZero() {
+		r.timeline.FirstTaskStart = now
+	}
+	r.dispatched = append(r.dispatched, t.index)
+	if id < len(r.busy) {
+		r.busy[id] = t
+	}
+	if r.trace != nil {
+		r.trace.add(TraceStep{Task: t.index, Worker: id, At: now.Sub(r.timeline.DispatchStart)})
+	}
+	r.releaseFlush()
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestReplayTrace(t *testing.T) {
+	tasks := func(r *Runner) {
+		for i := 0; i < 12; i++ {
+			d := time.Duration(rand.Intn(3)) * time.Millisecond
+			r.Add(func(int) { time.Sleep(d) })
+		}
+	}
+	r := New(time.Second, 3)
+	tasks(r)
+	recorded := r.RecordTrace()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() = %v, want nil", err)
+	}
+	want := recorded.Steps()
+	if len(want) != 12 {
+		t.Fatalf("the trace holds %d steps, want 12", len(want))
+	}
+
+	for run := 0; run < 3; run++ {
+		r := NewWithOptions(time.Second, 3, WithReplayTrace(NewTrace(want)))
+		tasks(r)
+		if err := r.Start(); err != nil {
+			t.Fatalf("Start() = %v on replay, want nil", err)
+		}
+		order := r.DispatchOrder()
+		results := r.Results()
+		for i, s := range want {
+			if order[i] != s.Task {
+				t.Fatalf("replay dispatched %v, want the order of %v", order, want)
+			}
+			if got := results[s.Task].Worker; got != s.Worker {
+				t.Fatalf("replay ran task %d on worker %d, want %d", s.Task, got, s.Worker)
+			}
+		}
+	}
+}